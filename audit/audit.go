@@ -0,0 +1,232 @@
+package audit
+
+import (
+	"fmt"
+	"sync"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Audit actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+const beforeRowsKey = "audit:before_rows"
+
+var (
+	mu        sync.RWMutex
+	audited   = make(map[string]bool) // schema names of opted-in models
+	callbacks sync.Once
+)
+
+// Register enables the audit log for the given models and installs the GORM callbacks on db.
+// Changes made through db.WithContext(ctx) carry the actor and request metadata found in ctx.
+func Register(db *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse audited model %T: %w", model, err)
+		}
+		mu.Lock()
+		audited[stmt.Schema.Name] = true
+		mu.Unlock()
+	}
+
+	var err error
+	callbacks.Do(func() {
+		err = registerCallbacks(db)
+	})
+	return err
+}
+
+// IsAudited reports whether changes to the named model (e.g. "WebUser") are recorded.
+func IsAudited(entityType string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return audited[entityType]
+}
+
+func registerCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("audit:after_create", afterCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("audit:before_update", loadBefore); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("audit:after_update", afterUpdate); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("audit:before_delete", loadBefore); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("audit:after_delete", afterDelete)
+}
+
+func shouldAudit(db *gorm.DB) bool {
+	return db.Error == nil && !db.DryRun && db.Statement.Schema != nil && IsAudited(db.Statement.Schema.Name)
+}
+
+func afterCreate(db *gorm.DB) {
+	if !shouldAudit(db) {
+		return
+	}
+
+	var entries []sharedModels.AuditLog
+	for _, row := range snapshotStruct(db) {
+		entries = append(entries, newEntry(db, ActionCreate, entityID(db.Statement.Schema, row), nil, row))
+	}
+	saveEntries(db, entries)
+}
+
+// loadBefore reads the rows targeted by an update or delete before they change.
+func loadBefore(db *gorm.DB) {
+	if !shouldAudit(db) {
+		return
+	}
+
+	rows, err := loadRows(db, targetConditions(db))
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: failed to load rows before change: %w", err))
+		return
+	}
+	db.InstanceSet(beforeRowsKey, rows)
+}
+
+func afterUpdate(db *gorm.DB) {
+	before, ok := beforeRows(db)
+	if !ok || !shouldAudit(db) || len(before) == 0 {
+		return
+	}
+
+	after, err := loadRows(db, primaryKeyConditions(db.Statement.Schema, before))
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: failed to load rows after change: %w", err))
+		return
+	}
+
+	afterByID := make(map[string]map[string]interface{}, len(after))
+	for _, row := range after {
+		afterByID[entityID(db.Statement.Schema, row)] = row
+	}
+
+	var entries []sharedModels.AuditLog
+	for _, old := range before {
+		id := entityID(db.Statement.Schema, old)
+		changedBefore, changedAfter := diff(old, afterByID[id])
+		if len(changedBefore) == 0 {
+			continue
+		}
+		entries = append(entries, newEntry(db, ActionUpdate, id, changedBefore, changedAfter))
+	}
+	saveEntries(db, entries)
+}
+
+func afterDelete(db *gorm.DB) {
+	before, ok := beforeRows(db)
+	if !ok || !shouldAudit(db) {
+		return
+	}
+
+	var entries []sharedModels.AuditLog
+	for _, row := range before {
+		entries = append(entries, newEntry(db, ActionDelete, entityID(db.Statement.Schema, row), row, nil))
+	}
+	saveEntries(db, entries)
+}
+
+func beforeRows(db *gorm.DB) ([]map[string]interface{}, bool) {
+	value, ok := db.InstanceGet(beforeRowsKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := value.([]map[string]interface{})
+	return rows, ok
+}
+
+func newEntry(db *gorm.DB, action, id string, before, after map[string]interface{}) sharedModels.AuditLog {
+	meta := utils.RequestMetaFromContext(db.Statement.Context)
+
+	entry := sharedModels.AuditLog{
+		EntityType: db.Statement.Schema.Name,
+		EntityID:   id,
+		Action:     action,
+		Actor:      utils.ActorFromContext(db.Statement.Context),
+		RequestID:  meta.RequestID,
+		IP:         meta.IP,
+	}
+
+	if action == ActionUpdate {
+		entry.ChangedFields = toJSON(sortedKeys(before))
+	}
+	if before != nil {
+		entry.Before = toJSON(redact(before))
+	}
+	if after != nil {
+		entry.After = toJSON(redact(after))
+	}
+	return entry
+}
+
+// saveEntries writes the entries on the statement's connection, so they commit or roll back with the change.
+func saveEntries(db *gorm.DB, entries []sharedModels.AuditLog) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&entries).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: failed to write audit log: %w", err))
+	}
+}
+
+// targetConditions rebuilds the WHERE of an update or delete, including primary keys taken from the model.
+func targetConditions(db *gorm.DB) []clause.Expression {
+	stmt := db.Statement
+	var conds []clause.Expression
+
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			conds = append(conds, where.Exprs...)
+		}
+	}
+
+	_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+	if len(values) > 0 {
+		conds = append(conds, clause.IN{Column: column, Values: values})
+	}
+
+	return conds
+}
+
+func primaryKeyConditions(sch *schema.Schema, rows []map[string]interface{}) []clause.Expression {
+	var conds []clause.Expression
+	for _, field := range sch.PrimaryFields {
+		values := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			values = append(values, row[field.DBName])
+		}
+		conds = append(conds, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Values: values})
+	}
+	return conds
+}
+
+// loadRows reads the current column values of the rows matching conds. Without conditions nothing is loaded,
+// so a global update (AllowGlobalUpdate) is not snapshotted in full.
+func loadRows(db *gorm.DB, conds []clause.Expression) ([]map[string]interface{}, error) {
+	if len(conds) == 0 {
+		return nil, nil
+	}
+
+	var rows []map[string]interface{}
+	err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Table(db.Statement.Schema.Table).
+		Clauses(clause.Where{Exprs: conds}).
+		Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Redacted replaces the value of sensitive columns in audit entries.
+const Redacted = "[REDACTED]"
+
+var (
+	sensitiveMu     sync.RWMutex
+	sensitiveFields = map[string]bool{
+		"password":      true,
+		"token":         true,
+		"secret":        true,
+		"refresh_token": true,
+	}
+)
+
+// AddSensitiveFields marks additional column names whose values are never written to the audit log.
+// Changes to them are still listed in ChangedFields.
+func AddSensitiveFields(columns ...string) {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+	for _, column := range columns {
+		sensitiveFields[strings.ToLower(column)] = true
+	}
+}
+
+func isSensitive(column string) bool {
+	sensitiveMu.RLock()
+	defer sensitiveMu.RUnlock()
+	return sensitiveFields[strings.ToLower(column)]
+}
+
+func redact(row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		if isSensitive(column) {
+			out[column] = Redacted
+			continue
+		}
+		out[column] = value
+	}
+	return out
+}
+
+// snapshotStruct converts the created struct (or slice of structs) into column => value maps.
+func snapshotStruct(db *gorm.DB) []map[string]interface{} {
+	stmt := db.Statement
+	var rows []map[string]interface{}
+
+	snapshot := func(rv reflect.Value) {
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		row := make(map[string]interface{})
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			value, _ := field.ValueOf(stmt.Context, rv)
+			row[field.DBName] = value
+		}
+		rows = append(rows, row)
+	}
+
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			snapshot(stmt.ReflectValue.Index(i))
+		}
+	case reflect.Struct:
+		snapshot(stmt.ReflectValue)
+	}
+	return rows
+}
+
+// diff returns the columns whose values differ, with their old and new values.
+func diff(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	changedBefore := make(map[string]interface{})
+	changedAfter := make(map[string]interface{})
+
+	for column, old := range before {
+		current := after[column]
+		if jsonEqual(old, current) {
+			continue
+		}
+		changedBefore[column] = old
+		changedAfter[column] = current
+	}
+	return changedBefore, changedAfter
+}
+
+// jsonEqual compares values by their JSON encoding, so []byte from the driver and datatypes.JSON compare equal.
+func jsonEqual(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	return string(ja) == string(jb)
+}
+
+func entityID(sch *schema.Schema, row map[string]interface{}) string {
+	parts := make([]string, 0, len(sch.PrimaryFields))
+	for _, field := range sch.PrimaryFields {
+		parts = append(parts, fmt.Sprint(row[field.DBName]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toJSON(v interface{}) datatypes.JSON {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return datatypes.JSON("null")
+	}
+	return datatypes.JSON(data)
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/model"
+	"github.com/DevdotSP/go-utils/respcode"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// Filter narrows an audit log query. Zero values are ignored.
+type Filter struct {
+	EntityType string
+	EntityID   string
+	Action     string
+	Actor      string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+}
+
+// Query returns one page of audit entries matching filter, newest first.
+func Query(ctx context.Context, db *gorm.DB, filter Filter, page, limit int) (*utils.PaginatedResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	where := map[string]interface{}{}
+	for column, value := range map[string]string{
+		"entity_type": filter.EntityType,
+		"entity_id":   filter.EntityID,
+		"action":      filter.Action,
+		"actor":       filter.Actor,
+		"request_id":  filter.RequestID,
+	} {
+		if value != "" {
+			where[column] = value
+		}
+	}
+
+	query := db.WithContext(ctx)
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	var entries []sharedModels.AuditLog
+	return utils.Paginate(query, &entries, page, limit, where, nil)
+}
+
+// History returns one page of the changes recorded for a single entity.
+func History(ctx context.Context, db *gorm.DB, entityType, entityID string, page, limit int) (*utils.PaginatedResult, error) {
+	return Query(ctx, db, Filter{EntityType: entityType, EntityID: entityID}, page, limit)
+}
+
+// HistoryHandler serves the history of one entity on a route such as /audit/:entity/:id,
+// where :entity is the model name (e.g. WebUser). Supports ?page=&limit=&action=.
+func HistoryHandler(db *gorm.DB) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		entityType := c.Params("entity")
+		entityID := c.Params("id")
+		if !IsAudited(entityType) || entityID == "" {
+			return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+		}
+
+		page := fiber.Query[int](c, "page", 1)
+		limit := fiber.Query[int](c, "limit", 20)
+
+		result, err := Query(c.Context(), db, Filter{
+			EntityType: entityType,
+			EntityID:   entityID,
+			Action:     c.Query("action"),
+		}, page, limit)
+		if err != nil {
+			return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+		}
+
+		return helper.JSONResponseWithDataPageDetails(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, result.Records, &model.PageDetails{
+			Page:       result.CurrentPage,
+			PageSize:   limit,
+			TotalItem:  int(result.TotalCount),
+			TotalPages: result.TotalPages,
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DevdotSP/go-utils/config"
+	"github.com/DevdotSP/go-utils/migration"
+	"github.com/DevdotSP/go-utils/utils"
+)
+
+// RunDrift compares shared-models with the live database.
+// "check" prints the drift report, "generate [dir]" also writes a migration file (default: migrations).
+func RunDrift(action string, args []string) {
+	if action != "check" && action != "generate" {
+		fmt.Println("❌ Usage: go run tool.go drift [check|generate] [dir] [--destructive]")
+		os.Exit(1)
+	}
+
+	utils.LoadEnv()
+	config.PostgreSQLConnect()
+
+	report, err := migration.DetectDrift(config.DB, migration.RegisteredModels()...)
+	if err != nil {
+		fmt.Printf("❌ Failed to detect schema drift: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(report)
+
+	if action == "check" {
+		if report.HasDrift() {
+			os.Exit(2)
+		}
+		return
+	}
+
+	if !report.HasDrift() {
+		return
+	}
+
+	dir := "migrations"
+	destructive := false
+	for _, arg := range args {
+		if arg == "--destructive" {
+			destructive = true
+		} else {
+			dir = arg
+		}
+	}
+
+	path, err := migration.WriteMigrationFile(config.DB, report, dir, destructive)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ %s generated successfully\n", path)
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DevdotSP/go-utils/mailer"
+	"github.com/joho/godotenv"
+)
+
+// RunEmail previews email templates. "list" prints the templates with sample data and
+// "preview <template> [locale] [dir]" renders one into <dir>/<template>.html and .txt (default: email-preview).
+func RunEmail(action string, args []string) {
+	switch action {
+	case "list":
+		for _, name := range mailer.Samples() {
+			fmt.Println(name)
+		}
+	case "preview":
+		if len(args) < 1 {
+			fmt.Println("❌ Usage: go run tool.go email preview <template> [locale] [dir]")
+			os.Exit(1)
+		}
+		previewEmail(args[0], argAt(args, 1, ""), argAt(args, 2, "email-preview"))
+	default:
+		fmt.Println("❌ Usage: go run tool.go email [list|preview <template> [locale] [dir]]")
+		os.Exit(1)
+	}
+}
+
+func previewEmail(name, locale, dir string) {
+	// Branding comes from the environment, possibly from a .env; a preview works without one
+	_ = godotenv.Load()
+
+	email, ok := mailer.Sample(name)
+	if !ok {
+		fmt.Printf("❌ No sample data for template %q\n", name)
+		os.Exit(1)
+	}
+	msg, err := mailer.LoadTemplates().Render(locale, email)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Printf("❌ Failed to create %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	files := map[string]string{
+		filepath.Join(dir, name+".html"): msg.HTML,
+		filepath.Join(dir, name+".txt"):  "Subject: " + msg.Subject + "\n\n" + msg.Text + "\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			fmt.Printf("❌ Failed to write %s: %v\n", path, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("✅ %s previewed in %s (subject: %s)\n", name, dir, msg.Subject)
+}
+
+func argAt(args []string, i int, fallback string) string {
+	if i < len(args) && args[i] != "" {
+		return args[i]
+	}
+	return fallback
+}
@@ -97,131 +97,103 @@ func generateBoilerplate(module, file string) string {
 		return fmt.Sprintf(`package controllers
 
 import (
-	"github.com/gofiber/fiber/v3"
-	"github.com/DevdotSP/go-utils/helper"
-	"github.com/DevdotSP/go-utils/respcode"
-	"%s/%s/services"
+	"github.com/DevdotSP/go-utils/repository"
 	"%s/%s/models"
+	"%s/%s/services"
 )
 
+// Controller serves the standard CRUD routes through the embedded CRUDController.
+// Add module specific handlers as methods on Controller.
 type Controller struct {
+	*repository.CRUDController[models.%s, int]
 	s *services.Service
 }
 
 func NewController(s *services.Service) *Controller {
-	return &Controller{s: s}
-}
-
-func (ctl *Controller) Index(c fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "List all %s"})
-}
-
-func (ctl *Controller) Show(c fiber.Ctx) error {
-	id := c.Params("id")
-	return c.JSON(fiber.Map{"message": "Show %s", "id": id})
-}
-
-func (ctl *Controller) Store(c fiber.Ctx) error {
-		var request models.%s
-		if err := c.Bind().Body(&request); err != nil {
-		return helper.JSONResponseWithData(c, respcode.ERR_CODE_400, "Invalid input", nil)
+	return &Controller{
+		CRUDController: repository.NewCRUDController(s.Repository()),
+		s:              s,
 	}
-
-	// Call the service layer to process the role creation
-
-	return helper.JSONResponse(c, respcode.SUC_CODE_201, "%s store successfully")
 }
-
-func (ctl *Controller) Update(c fiber.Ctx) error {
-	id := c.Params("id")
-		var request models.%s
-		if err := c.Bind().Body(&request); err != nil {
-		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid input")
-	}
-
-
-	return helper.JSONResponse(c, respcode.SUC_CODE_204, "%s update successfully with ID" + id)
-}
-
-func (ctl *Controller) Delete(c fiber.Ctx) error {
-	id := c.Params("id")
-	return c.JSON(fiber.Map{"message": "Delete %s", "id": id})
-}
-`, basePath, module, basePath, module, module, module, capitalModule, module, capitalModule, module, module)
+`, basePath, module, basePath, module, capitalModule)
 
 	case "interface.go":
 		return fmt.Sprintf(`package repositories
 
+import (
+	"context"
+
+	"github.com/DevdotSP/go-utils/repository"
+	"github.com/DevdotSP/go-utils/utils"
+	"%s/%s/models"
+)
+
 type %s interface {
+	Create(ctx context.Context, entity *models.%s) error
+	FindByID(ctx context.Context, id int, preloads ...string) (*models.%s, error)
+	FindAll(ctx context.Context, filter repository.Filter) ([]models.%s, error)
+	Paginate(ctx context.Context, page, limit int, filter repository.Filter) (*utils.PaginatedResult, error)
+	Update(ctx context.Context, entity *models.%s) error
+	Delete(ctx context.Context, id int) error
 	// Define your repository methods here
 }
-`, modulePascal)
+`, basePath, module, modulePascal, capitalModule, capitalModule, capitalModule, capitalModule)
 
 	case "repository.go":
 		return fmt.Sprintf(`package repositories
 
 import (
+	"github.com/DevdotSP/go-utils/repository"
 	"gorm.io/gorm"
 	"%s/%s/models"
 )
 
+var _ %s = (*Repository)(nil)
+
+// Repository embeds the generic CRUD repository; add custom queries as methods.
 type Repository struct {
-	db *gorm.DB
+	*repository.Repository[models.%s, int]
 }
 
 func NewRepository(db *gorm.DB) *Repository {
-	return &Repository{db: db}
-}
-
-func (r *Repository) Create(entity *models.%s) error {
-	return r.db.Create(entity).Error
-}
-
-func (r *Repository) FindByID(id uint) (*models.%s, error) {
-	var entity models.%s
-	if err := r.db.First(&entity, id).Error; err != nil {
-		return nil, err
-	}
-	return &entity, nil
-}
-
-func (r *Repository) FindAll() ([]models.%s, error) {
-	var entities []models.%s
-	if err := r.db.Find(&entities).Error; err != nil {
-		return nil, err
-	}
-	return entities, nil
-}
-
-func (r *Repository) Update(entity *models.%s) error {
-	return r.db.Save(entity).Error
+	return &Repository{Repository: repository.MustNewRepository[models.%s, int](db)}
 }
-
-func (r *Repository) Delete(id uint) error {
-	return r.db.Delete(&models.%s{}, id).Error
-}
-`, basePath, module, capitalModule, capitalModule, capitalModule, capitalModule, capitalModule, capitalModule, capitalModule)
+`, basePath, module, modulePascal, capitalModule, capitalModule)
 
 	case "service.go":
 		return fmt.Sprintf(`package services
 
-import "%s/%s/repositories"
+import (
+	"github.com/DevdotSP/go-utils/repository"
+	"%s/%s/models"
+	"%s/%s/repositories"
+)
 
 type Service struct {
-	r repositories.%s
+	r *repositories.Repository
 }
 
-func NewService(r repositories.%s) *Service {
+func NewService(r *repositories.Repository) *Service {
 	return &Service{r: r}
 }
-`, basePath, module, modulePascal, modulePascal)
+
+// Repository exposes the underlying repository to the CRUD controller.
+func (s *Service) Repository() *repository.Repository[models.%s, int] {
+	return s.r.Repository
+}
+`, basePath, module, basePath, module, capitalModule)
 
 	case "model.go":
 		return fmt.Sprintf(`package models
 
-		type %s struct {}  // Define your model structs here
-
+import "time"
 
+type %s struct {
+	ID        int       `+"`"+`gorm:"primaryKey;autoIncrement" json:"id"`+"`"+`
+	CreatedAt time.Time `+"`"+`gorm:"autoCreateTime;type:timestamptz" json:"created_at"`+"`"+`
+	UpdatedAt time.Time `+"`"+`gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`+"`"+`
+	// Define your model fields here
+}
 `, capitalModule)
 
 	case "routes.go":
@@ -233,19 +205,16 @@ import (
 	"%s/%s/services"
 
 	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
 )
 
-func Register%sRoute(app fiber.Router) {
-	r := repositories.NewRepository(nil) // TODO: Replace nil with actual DB
+func Register%sRoute(app fiber.Router, db *gorm.DB) {
+	r := repositories.NewRepository(db)
 	s := services.NewService(r)
 	c := controllers.NewController(s)
 
 	group := app.Group("/%s")
-	group.Get("/", c.Index)
-	group.Get("/:id", c.Show)
-	group.Post("/", c.Store)
-	group.Put("/:id", c.Update)
-	group.Delete("/:id", c.Delete)
+	c.RegisterRoutes(group)
 }
 `, basePath, module, basePath, module, basePath, module, modulePascal, moduleCamel)
 	}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/DevdotSP/go-utils/config"
+	importingcsv "github.com/DevdotSP/go-utils/importing-csv"
+	"github.com/DevdotSP/go-utils/migration"
+	"github.com/DevdotSP/go-utils/tenant"
+	"github.com/DevdotSP/go-utils/utils"
+)
+
+// RunTenant manages tenant schemas.
+// "create <id>" creates and migrates the schema of a new tenant and imports the address CSV files,
+// "migrate [id]" migrates one tenant or all of them, "list" prints the existing tenants.
+func RunTenant(action string, args []string) {
+	if (action == "create" && len(args) == 0) || (action != "create" && action != "migrate" && action != "list") {
+		fmt.Println("❌ Usage: go run tool.go tenant [create <id>|migrate [id]|list]")
+		os.Exit(1)
+	}
+
+	utils.LoadEnv()
+	config.PostgreSQLConnect()
+
+	switch action {
+	case "create":
+		migration.MigrationTableForTenant(args[0])
+		importingcsv.InsertCSVForTenant(args[0])
+	case "migrate":
+		if len(args) > 0 {
+			migration.MigrationTableForTenant(args[0])
+		} else {
+			migration.MigrationAllTenants()
+		}
+	case "list":
+		ids, err := tenant.List(context.Background(), config.DB)
+		if err != nil {
+			fmt.Printf("❌ Failed to list tenants: %v\n", err)
+			os.Exit(1)
+		}
+		for _, id := range ids {
+			fmt.Printf("%s\t%s\n", id, tenant.SchemaName(id))
+		}
+	}
+}
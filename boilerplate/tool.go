@@ -11,11 +11,18 @@ func main() {
 	//go run package/boilerplate/tool.go module customer
 	//["gcloud", "database", "firebase"]
 	//go run package/boilerplate/tool.go config database
+	//go run package/boilerplate/tool.go drift check
+	//go run package/boilerplate/tool.go drift generate migrations
+	//go run package/boilerplate/tool.go tenant create acme
+	//go run package/boilerplate/tool.go email preview verify_email
 
 	if len(os.Args) < 3 {
 		fmt.Println("Usage:")
 		fmt.Println("  go run tool.go module [ModuleName]")
 		fmt.Println("  go run tool.go config [ComponentName]")
+		fmt.Println("  go run tool.go drift [check|generate] [dir] [--destructive]")
+		fmt.Println("  go run tool.go tenant [create <id>|migrate [id]|list]")
+		fmt.Println("  go run tool.go email [list|preview <template> [locale] [dir]]")
 		return
 	}
 
@@ -27,6 +34,12 @@ func main() {
 		command.GenerateModule(arg) // go run package/boilerplate/tool.go module customer
 	case "config":
 		command.GenerateConfig(arg) // go run package/boilerplate/tool.go config database
+	case "drift":
+		command.RunDrift(arg, os.Args[3:]) // go run package/boilerplate/tool.go drift check
+	case "tenant":
+		command.RunTenant(arg, os.Args[3:]) // go run package/boilerplate/tool.go tenant migrate
+	case "email":
+		command.RunEmail(arg, os.Args[3:]) // go run package/boilerplate/tool.go email preview verify_email
 	default:
 		fmt.Println("Unknown command:", cmd)
 	}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"strconv"
 
-	"github.com/DevdotSP/go-utils/utils" // Update with your actual repo path
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/tenant"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -16,33 +20,49 @@ var (
 	PgxPool *pgxpool.Pool
 )
 
+// DatabaseConfig is the PostgreSQL connection opened by PostgreSQLConnect
+type DatabaseConfig struct {
+	Host     string `env:"DB_HOST" default:"localhost"`
+	User     string `env:"DB_USER" default:"postgres"`
+	Password string `env:"DB_PASSWORD,required,secret"`
+	Name     string `env:"DB_NAME" default:"portfolio"`
+	Port     int    `env:"DB_PORT" default:"5432"`
+	SSLMode  string `env:"DB_SSLMODE" default:"disable"`
+}
+
+// URL returns the connection URL, with the credentials escaped
+func (c DatabaseConfig) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
 func PostgreSQLConnect() {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		utils.GetEnv("DB_HOST", "localhost"),
-		utils.GetEnv("DB_USER", "postgres"),
-		utils.GetEnv("DB_PASSWORD", "@Light1114"),
-		utils.GetEnv("DB_NAME", "portfolio"),
-		utils.GetEnv("DB_PORT", "5432"),
-	)
-
-	pgxDSN := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s",
-		utils.GetEnv("DB_USER", "postgres"),
-		utils.GetEnv("DB_PASSWORD", "@Light1114"),
-		utils.GetEnv("DB_HOST", "localhost"),
-		utils.GetEnv("DB_PORT", "5432"),
-		utils.GetEnv("DB_NAME", "portfolio"),
-	)
-
-	var err error
+	cfg, err := NewLoader[DatabaseConfig]().Load()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	dsn := cfg.URL()
+
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to database: %v", err)
 	}
 	log.Println("✅ Database connected")
 
-	PgxPool, err = pgxpool.New(context.Background(), pgxDSN)
+	if err := sharedModels.RegisterCallbacks(DB); err != nil {
+		log.Fatalf("❌ Failed to register GORM callbacks: %v", err)
+	}
+	if err := tenant.Register(DB); err != nil {
+		log.Fatalf("❌ Failed to register tenant callbacks: %v", err)
+	}
+
+	PgxPool, err = pgxpool.New(context.Background(), dsn)
 	if err != nil {
 		log.Fatalf("❌ Failed to create pgx connection pool: %v", err)
 	}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+)
+
+// Sources of a setting, from lowest to highest precedence
+const (
+	SourceDefault    = "default"
+	SourceFile       = "file"
+	SourceEnvFile    = ".env"
+	SourceEnv        = "env"
+	SourceParameters = "parameters"
+)
+
+// Loader binds configuration into a struct T from layered sources. Each field names its setting with
+// an env tag and may set a default:
+//
+//	type AppConfig struct {
+//		Port        int           `env:"PORT" default:"8080"`
+//		DBPassword  string        `env:"DB_PASSWORD,required,secret"`
+//		ReadTimeout time.Duration `env:"READ_TIMEOUT,reload" default:"15s"`
+//	}
+//
+// Later sources win: defaults, then Files, then EnvFile, then the environment, then the system
+// parameters in DB. Options after the name: required fails Load when no source sets the field, secret
+// hides the value in Print, and reload lets Reload change it at runtime. Nested structs are flattened.
+// Supported types are strings, bools, ints, uints, floats, time.Duration, comma-separated []string and
+// encoding.TextUnmarshaler.
+type Loader[T any] struct {
+	Files       []string // YAML or JSON files, missing ones are skipped
+	EnvFile     string   // Skipped when missing
+	DB          *gorm.DB // Source of system parameters, skipped when nil; set it once connected and Load again
+	ParamPrefix string   // Only parameters under this prefix are read, e.g. "config." for "config.mail.host"
+
+	// OnReload is called after Reload changed reloadable settings, with their names
+	OnReload func(cfg *T, changed []string)
+
+	current  atomic.Pointer[T]
+	mu       sync.Mutex
+	settings []Setting
+	pending  map[string]string // Values waiting for a restart, logged once
+}
+
+// Setting is the effective value of one field and where it came from.
+type Setting struct {
+	Name     string
+	Value    string
+	Source   string // "default", "file:<path>", ".env", "env", "parameters" or "" when unset
+	Required bool
+	Secret   bool
+	Reload   bool
+}
+
+// FieldError is one problem found by Load.
+type FieldError struct {
+	Name   string
+	Source string
+	Err    error
+}
+
+// LoadError reports every problem found by Load at once.
+type LoadError struct {
+	Problems []FieldError
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s: %v", p.Name, p.Err)
+		if p.Source != "" {
+			fmt.Fprintf(&b, " (from %s)", p.Source)
+		}
+	}
+	return b.String()
+}
+
+// NewLoader returns a Loader reading .env and the environment.
+func NewLoader[T any](files ...string) *Loader[T] {
+	return &Loader[T]{Files: files, EnvFile: ".env"}
+}
+
+// MustLoad loads the configuration, prints it and stops the program with the report of every problem.
+func MustLoad[T any](l *Loader[T]) *T {
+	cfg, err := l.Load()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	l.Print(log.Writer())
+	return cfg
+}
+
+// Load reads every source, validates the result and makes it the current configuration.
+func (l *Loader[T]) Load() (*T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cfg := new(T)
+	settings, err := l.resolve(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	l.settings = settings
+	l.current.Store(cfg)
+	return cfg, nil
+}
+
+// Get returns the current configuration. It is replaced, never changed, by Reload: keep the pointer
+// for a consistent snapshot or call Get again for the latest reloadable values.
+func (l *Loader[T]) Get() *T {
+	return l.current.Load()
+}
+
+// Reload reads every source again and applies the changes of reload fields, returning their names.
+// Changes of other fields only take effect after a restart and are logged. Nothing is applied when the
+// sources are invalid.
+func (l *Loader[T]) Reload() ([]string, error) {
+	l.mu.Lock()
+	current := l.current.Load()
+	if current == nil {
+		l.mu.Unlock()
+		return nil, fmt.Errorf("configuration is not loaded")
+	}
+
+	next := new(T)
+	*next = *current
+	settings, err := l.resolve(next, l.settings)
+	if err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+
+	var changed []string
+	for i, setting := range settings {
+		if setting.Value != l.settings[i].Value {
+			changed = append(changed, setting.Name)
+		}
+	}
+	l.settings = settings
+	l.current.Store(next)
+	onReload := l.OnReload
+	l.mu.Unlock()
+
+	if len(changed) > 0 && onReload != nil {
+		onReload(next, changed)
+	}
+	return changed, nil
+}
+
+// Watch calls Reload every interval until ctx is done.
+func (l *Loader[T]) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := l.Reload()
+			if err != nil {
+				log.Printf("⚠️ Configuration reload failed: %v", err)
+			} else if len(changed) > 0 {
+				log.Printf("✅ Configuration reloaded: %s", strings.Join(changed, ", "))
+			}
+		}
+	}
+}
+
+// Settings returns the effective settings, with secrets redacted.
+func (l *Loader[T]) Settings() []Setting {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	settings := make([]Setting, len(l.settings))
+	for i, setting := range l.settings {
+		if setting.Secret && setting.Value != "" {
+			setting.Value = utils.Redacted
+		}
+		settings[i] = setting
+	}
+	return settings
+}
+
+// Print writes the effective configuration to w, one setting per line, with secrets redacted.
+func (l *Loader[T]) Print(w io.Writer) {
+	fmt.Fprintln(w, "Effective configuration:")
+	for _, setting := range l.Settings() {
+		source := setting.Source
+		if source == "" {
+			source = "unset"
+		}
+		fmt.Fprintf(w, "  %s=%s (%s)\n", setting.Name, setting.Value, source)
+	}
+}
+
+// resolve sets the fields of cfg from the sources. With the previous settings, only reload fields
+// are set and the others keep their previous value.
+func (l *Loader[T]) resolve(cfg *T, previous []Setting) ([]Setting, error) {
+	fields, problems := bindFields(reflect.ValueOf(cfg).Elem(), nil)
+	layers, sourceProblems := l.layers()
+	problems = append(problems, sourceProblems...)
+
+	settings := make([]Setting, len(fields))
+	for i, field := range fields {
+		setting := Setting{Name: field.name, Required: field.required, Secret: field.secret, Reload: field.reload}
+		if field.hasDefault {
+			setting.Value, setting.Source = field.def, SourceDefault
+		}
+		for _, layer := range layers {
+			if value, ok := layer.values[field.name]; ok {
+				setting.Value, setting.Source = value, layer.name
+			}
+		}
+
+		if previous != nil && !field.reload {
+			if setting.Value != previous[i].Value && setting.Value != l.pending[field.name] {
+				if l.pending == nil {
+					l.pending = map[string]string{}
+				}
+				l.pending[field.name] = setting.Value
+				log.Printf("⚠️ %s changed in %s, restart to apply it", field.name, setting.Source)
+			}
+			settings[i] = previous[i]
+			continue
+		}
+		settings[i] = setting
+
+		if field.required && strings.TrimSpace(setting.Value) == "" {
+			problems = append(problems, FieldError{Name: field.name, Source: setting.Source, Err: fmt.Errorf("required")})
+			continue
+		}
+		if setting.Source == "" {
+			field.value.Set(reflect.Zero(field.value.Type()))
+			continue
+		}
+		if err := setValue(field.value, setting.Value); err != nil {
+			if field.secret {
+				// Parse errors quote the value
+				err = fmt.Errorf("invalid %s", field.value.Type())
+			}
+			problems = append(problems, FieldError{Name: field.name, Source: setting.Source, Err: err})
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &LoadError{Problems: problems}
+	}
+	return settings, nil
+}
+
+// field is a leaf field of the configuration struct
+type field struct {
+	name       string
+	def        string
+	hasDefault bool
+	required   bool
+	secret     bool
+	reload     bool
+	value      reflect.Value
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// bindFields lists the tagged fields of v, flattening nested structs.
+func bindFields(v reflect.Value, fields []field) ([]field, []FieldError) {
+	var problems []FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag, tagged := sf.Tag.Lookup("env")
+		fv := v.Field(i)
+
+		if !tagged {
+			if sf.Type.Kind() == reflect.Struct && !reflect.PointerTo(sf.Type).Implements(textUnmarshalerType) {
+				var nested []FieldError
+				fields, nested = bindFields(fv, fields)
+				problems = append(problems, nested...)
+			}
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		f := field{name: strings.TrimSpace(parts[0]), value: fv}
+		f.def, f.hasDefault = sf.Tag.Lookup("default")
+		for _, option := range parts[1:] {
+			switch strings.TrimSpace(option) {
+			case "required":
+				f.required = true
+			case "secret":
+				f.secret = true
+			case "reload":
+				f.reload = true
+			default:
+				problems = append(problems, FieldError{Name: f.name, Err: fmt.Errorf("unknown option %q", option)})
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, problems
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses s into v according to its type.
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	s = strings.TrimSpace(s)
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		values := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = reflect.Append(values, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(values)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// layer is the settings of one source by name
+type layer struct {
+	name   string
+	values map[string]string
+}
+
+// layers reads every source of l, from lowest to highest precedence.
+func (l *Loader[T]) layers() ([]layer, []FieldError) {
+	var layers []layer
+	var problems []FieldError
+
+	for _, path := range l.Files {
+		values, err := readFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			problems = append(problems, FieldError{Name: path, Err: err})
+		default:
+			layers = append(layers, layer{name: SourceFile + ":" + path, values: values})
+		}
+	}
+
+	if l.EnvFile != "" {
+		values, err := godotenv.Read(l.EnvFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			problems = append(problems, FieldError{Name: l.EnvFile, Err: err})
+		default:
+			layers = append(layers, layer{name: SourceEnvFile, values: values})
+		}
+	}
+
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			env[name] = value
+		}
+	}
+	layers = append(layers, layer{name: SourceEnv, values: env})
+
+	if l.DB != nil {
+		values, err := l.readParameters()
+		if err != nil {
+			problems = append(problems, FieldError{Name: SourceParameters, Err: err})
+		} else {
+			layers = append(layers, layer{name: SourceParameters, values: values})
+		}
+	}
+	return layers, problems
+}
+
+// readFile reads a YAML or JSON file. Nested keys are joined with underscores and upper-cased, so
+// {"db": {"host": "x"}} sets DB_HOST; lists are joined with commas.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".json":
+		err = json.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported configuration file type %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	values := map[string]string{}
+	flatten(values, "", tree)
+	return values, nil
+}
+
+func flatten(values map[string]string, prefix string, tree map[string]interface{}) {
+	for key, value := range tree {
+		name := settingName(key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(values, name, v)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = scalar(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[name] = scalar(v)
+		}
+	}
+}
+
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// readParameters reads the system parameters under ParamPrefix, named like the files: "mail.host" sets MAIL_HOST.
+func (l *Loader[T]) readParameters() (map[string]string, error) {
+	var params []sharedModels.SystemParameter
+	query := l.DB.Select("key", "value")
+	if l.ParamPrefix != "" {
+		query = query.Where("key LIKE ?", strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(l.ParamPrefix)+"%")
+	}
+	if err := query.Find(&params).Error; err != nil {
+		return nil, fmt.Errorf("failed to read system parameters: %w", err)
+	}
+
+	values := map[string]string{}
+	for _, param := range params {
+		values[settingName(strings.TrimPrefix(param.Key, l.ParamPrefix))] = param.Value
+	}
+	return values, nil
+}
+
+// settingName converts a file key or parameter key to the name of a setting
+func settingName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
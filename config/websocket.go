@@ -1,39 +1,617 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"log"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/DevdotSP/go-utils/utils"
 	"github.com/gofiber/websocket/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Hub defaults
+const (
+	DefaultSendBuffer     = 256
+	DefaultPongWait       = 60 * time.Second
+	DefaultPingInterval   = DefaultPongWait * 9 / 10
+	DefaultWriteWait      = 10 * time.Second
+	DefaultMaxMessageSize = 64 * 1024
 )
 
+// Hub errors
 var (
-	clients = make(map[string]*websocket.Conn)
-	mu      sync.Mutex
+	ErrHubClosed     = errors.New("websocket hub is closed")
+	ErrRoomForbidden = errors.New("not allowed to join room")
 )
 
+// Hub tracks the WebSocket connections of users and the rooms they joined. A user may hold any
+// number of connections, e.g. one per browser tab, and every one of them receives what is sent to the user.
+//
+// Each connection gets its own write goroutine fed by a bounded buffer: a client that cannot keep up
+// is disconnected instead of blocking the sender. Connections that stop answering pings are evicted.
+type Hub struct {
+	SendBuffer     int           // Messages queued per connection before it is dropped as too slow
+	PingInterval   time.Duration // Must be shorter than PongWait
+	PongWait       time.Duration // Time allowed without any frame from the client
+	WriteWait      time.Duration // Time allowed to write a single frame
+	MaxMessageSize int64         // Largest inbound message accepted
+
+	// RoomRoles restricts rooms to the listed roles; a key ending in "*" matches every room with that prefix.
+	// Rooms not listed are open to every connection. Authorize, when set, replaces this check.
+	RoomRoles map[string][]string
+	Authorize func(c *Client, room string) bool
+
+	OnConnect    func(c *Client)
+	OnMessage    func(c *Client, message []byte)
+	OnDisconnect func(c *Client)
+
+	// InstanceID identifies this hub on the backplane
+	InstanceID string
+	// BackplaneBuffer is the number of messages queued for the backplane before they are dropped
+	BackplaneBuffer int
+	// PresenceInterval is how often this hub shares its users and rooms with the other instances
+	PresenceInterval time.Duration
+
+	mu        sync.RWMutex
+	backplane Backplane
+	outbox    chan BackplaneMessage
+	users     map[string]map[*Client]bool
+	rooms     map[string]map[*Client]bool
+	tokens    map[string]map[*Client]bool
+	closed    bool
+	wg        sync.WaitGroup
+
+	ackMu sync.Mutex
+	acks  map[string]*ackWaiter
+
+	presenceMu sync.RWMutex
+	presence   map[string]*instancePresence // By InstanceID
+
+	watchRevoked sync.Once
+}
+
+// NewHub returns an empty Hub using the default limits.
+func NewHub() *Hub {
+	return &Hub{
+		SendBuffer:     DefaultSendBuffer,
+		PingInterval:   DefaultPingInterval,
+		PongWait:       DefaultPongWait,
+		WriteWait:      DefaultWriteWait,
+		MaxMessageSize: DefaultMaxMessageSize,
+		InstanceID:     utils.GenerateUUID(),
+		users:          make(map[string]map[*Client]bool),
+		rooms:          make(map[string]map[*Client]bool),
+		tokens:         make(map[string]map[*Client]bool),
+	}
+}
+
+// DefaultHub is the hub used by WebSocketHandler.
+var DefaultHub = NewHub()
+
+// WebSocketHandler authenticates a connection with DefaultAuthConfig and serves it on DefaultHub.
+// The user is taken from the token; an :id path param, if present, must match it.
 func WebSocketHandler(c *websocket.Conn) {
-	userID := c.Params("id")
-	if userID == "" {
-		log.Println("Missing user ID")
-		return
+	if err := DefaultHub.ServeAuthenticated(c, DefaultAuthConfig); err != nil {
+		log.Printf("⚠️ WebSocket rejected: %v", err)
 	}
+}
+
+// Client is one WebSocket connection of a user.
+type Client struct {
+	UserID string
+	Claims jwt.MapClaims // Set for authenticated connections
+	Roles  []string
 
-	mu.Lock()
-	clients[userID] = c
-	mu.Unlock()
+	token string
+
+	hub   *Hub
+	conn  *websocket.Conn
+	send  chan []byte
+	rooms map[string]bool       // Guarded by hub.mu
+	acks  map[string]pendingAck // Events it may acknowledge, by ID; guarded by hub.ackMu
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+// Conn returns the underlying connection, e.g. to read its Locals, Params or Query.
+func (c *Client) Conn() *websocket.Conn {
+	return c.conn
+}
 
+// Send queues message for this connection. It returns false when the connection is closed, and
+// closes the connection when its send buffer is full.
+func (c *Client) Send(message []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	case <-c.done:
+		return false
+	default:
+		log.Printf("⚠️ WebSocket send buffer full for user %s, disconnecting", c.UserID)
+		c.Close(websocket.ClosePolicyViolation, "Send buffer full")
+		return false
+	}
+}
+
+// Join subscribes the connection to room.
+func (c *Client) Join(room string) error {
+	return c.hub.Join(c, room)
+}
+
+// Leave unsubscribes the connection from room.
+func (c *Client) Leave(room string) {
+	c.hub.Leave(c, room)
+}
+
+// Rooms returns the rooms the connection joined.
+func (c *Client) Rooms() []string {
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// HasRole reports whether the connection was authenticated with role.
+func (c *Client) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Close sends a close frame with code and text and disconnects the client. It does not block.
+func (c *Client) Close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// Done is closed when the connection is closing.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Serve registers conn for userID and pumps its messages until it disconnects. It must be called
+// from the websocket.New handler, which it blocks, as the connection is released when the handler returns.
+func (h *Hub) Serve(conn *websocket.Conn, userID string) error {
+	return h.serve(h.newClient(conn, userID))
+}
+
+func (h *Hub) newClient(conn *websocket.Conn, userID string) *Client {
+	return &Client{
+		UserID: userID,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, h.sendBuffer()),
+		rooms:  make(map[string]bool),
+		done:   make(chan struct{}),
+	}
+}
+
+func (h *Hub) serve(c *Client) error {
+	conn := c.conn
+	if err := h.register(c); err != nil {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseServiceRestart, "Server shutting down"), time.Now().Add(h.writeWait()))
+		conn.Close()
+		return err
+	}
+	defer h.wg.Done()
+
+	// The token may have been revoked between the handshake and register
+	if c.token != "" {
+		if _, err := utils.ValidateToken(c.token); err != nil {
+			c.Close(websocket.ClosePolicyViolation, "Token revoked")
+		}
+	}
+
+	if h.OnConnect != nil {
+		h.OnConnect(c)
+	}
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.writePump()
+	}()
+
+	c.readPump()
+	c.Close(websocket.CloseNormalClosure, "")
+	<-written
+
+	h.unregister(c)
+	if h.OnDisconnect != nil {
+		h.OnDisconnect(c)
+	}
+	return nil
+}
+
+func (h *Hub) register(c *Client) error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return ErrHubClosed
+	}
+	first := h.users[c.UserID] == nil
+	if first {
+		h.users[c.UserID] = make(map[*Client]bool)
+	}
+	h.users[c.UserID][c] = true
+	if c.token != "" {
+		if h.tokens[c.token] == nil {
+			h.tokens[c.token] = make(map[*Client]bool)
+		}
+		h.tokens[c.token][c] = true
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+
+	if first {
+		h.announce(userKey(c.UserID), true)
+	}
+	return nil
+}
+
+func (h *Hub) unregister(c *Client) {
+	var gone []string
+	h.mu.Lock()
+	delete(h.users[c.UserID], c)
+	if h.users[c.UserID] != nil && len(h.users[c.UserID]) == 0 {
+		delete(h.users, c.UserID)
+		gone = append(gone, userKey(c.UserID))
+	}
+	if c.token != "" {
+		delete(h.tokens[c.token], c)
+		if len(h.tokens[c.token]) == 0 {
+			delete(h.tokens, c.token)
+		}
+	}
+	for room := range c.rooms {
+		delete(h.rooms[room], c)
+		if len(h.rooms[room]) == 0 {
+			delete(h.rooms, room)
+			gone = append(gone, roomKey(room))
+		}
+	}
+	c.rooms = map[string]bool{}
+	h.mu.Unlock()
+
+	for _, key := range gone {
+		h.announce(key, false)
+	}
+}
+
+// readPump reads until the connection fails or a pong is missed, handing messages to OnMessage.
+func (c *Client) readPump() {
+	h := c.hub
+	c.conn.SetReadLimit(h.maxMessageSize())
+	c.conn.SetReadDeadline(time.Now().Add(h.pongWait()))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(h.pongWait()))
+	})
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				log.Printf("⚠️ WebSocket of user %s closed: %v", c.UserID, err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(h.pongWait()))
+
+		if h.OnMessage != nil {
+			h.OnMessage(c, message)
+		}
+	}
+}
+
+// writePump is the only writer of the connection. It closes the connection when it returns,
+// which also stops readPump.
+func (c *Client) writePump() {
+	h := c.hub
+	ticker := time.NewTicker(h.pingInterval())
 	defer func() {
-		mu.Lock()
-		delete(clients, userID)
-		mu.Unlock()
-		c.Close()
+		ticker.Stop()
+		c.conn.Close()
 	}()
 
 	for {
-		if _, _, err := c.ReadMessage(); err != nil {
-			log.Printf("User %s disconnected", userID)
-			break
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(h.writeWait()))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeWait())); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText), time.Now().Add(h.writeWait()))
+			return
 		}
 	}
 }
 
+// SendToUser queues message for every connection of userID, here and on the instances holding its other
+// connections when a backplane is in use, and returns how many local connections accepted it.
+func (h *Hub) SendToUser(userID string, message []byte) int {
+	h.route(userKey(userID), BackplaneMessage{Kind: KindUser, Target: userID, Payload: message})
+	return h.sendToUser(userID, message)
+}
+
+func (h *Hub) sendToUser(userID string, message []byte) int {
+	h.mu.RLock()
+	clients := collect(h.users[userID])
+	h.mu.RUnlock()
+	return send(clients, message)
+}
+
+// Publish queues message for every connection in room, here and on the instances holding its other
+// connections when a backplane is in use, and returns how many local connections accepted it.
+func (h *Hub) Publish(room string, message []byte) int {
+	h.route(roomKey(room), BackplaneMessage{Kind: KindRoom, Target: room, Payload: message})
+	return h.publish(room, message)
+}
+
+func (h *Hub) publish(room string, message []byte) int {
+	return send(h.members(room), message)
+}
+
+// members returns the connections in room on this instance.
+func (h *Hub) members(room string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return collect(h.rooms[room])
+}
+
+// Broadcast queues message for every connection, on every instance when a backplane is in use,
+// and returns how many local connections accepted it.
+func (h *Hub) Broadcast(message []byte) int {
+	h.forward(BackplaneMessage{Kind: KindBroadcast, Payload: message})
+	return h.broadcast(message)
+}
+
+func (h *Hub) broadcast(message []byte) int {
+	h.mu.RLock()
+	var clients []*Client
+	for _, conns := range h.users {
+		clients = append(clients, collect(conns)...)
+	}
+	h.mu.RUnlock()
+	return send(clients, message)
+}
+
+// Join subscribes c to room, provided its roles allow it.
+func (h *Hub) Join(c *Client, room string) error {
+	if !h.allowed(c, room) {
+		return ErrRoomForbidden
+	}
+
+	h.mu.Lock()
+	// Only registered connections, so a closed client does not leak into rooms
+	if !h.users[c.UserID][c] {
+		h.mu.Unlock()
+		return nil
+	}
+	first := h.rooms[room] == nil
+	if first {
+		h.rooms[room] = make(map[*Client]bool)
+	}
+	h.rooms[room][c] = true
+	c.rooms[room] = true
+	h.mu.Unlock()
+
+	if first {
+		h.announce(roomKey(room), true)
+	}
+	return nil
+}
+
+func (h *Hub) allowed(c *Client, room string) bool {
+	if h.Authorize != nil {
+		return h.Authorize(c, room)
+	}
+
+	roles, ok := h.RoomRoles[room]
+	if !ok {
+		// The longest matching prefix wins
+		match := ""
+		for pattern, patternRoles := range h.RoomRoles {
+			prefix, isPrefix := strings.CutSuffix(pattern, "*")
+			if isPrefix && strings.HasPrefix(room, prefix) && len(prefix) >= len(match) {
+				match, roles, ok = prefix, patternRoles, true
+			}
+		}
+	}
+	if !ok {
+		return true
+	}
+	for _, role := range roles {
+		if c.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Leave unsubscribes c from room.
+func (h *Hub) Leave(c *Client, room string) {
+	h.mu.Lock()
+	last := h.rooms[room] != nil && h.rooms[room][c] && len(h.rooms[room]) == 1
+	delete(h.rooms[room], c)
+	if len(h.rooms[room]) == 0 {
+		delete(h.rooms, room)
+	}
+	delete(c.rooms, room)
+	h.mu.Unlock()
+
+	if last {
+		h.announce(roomKey(room), false)
+	}
+}
+
+// JoinUser subscribes every current connection of userID allowed in room to it.
+func (h *Hub) JoinUser(userID, room string) {
+	for _, c := range h.Clients(userID) {
+		h.Join(c, room)
+	}
+}
+
+// LeaveUser unsubscribes every connection of userID from room.
+func (h *Hub) LeaveUser(userID, room string) {
+	for _, c := range h.Clients(userID) {
+		h.Leave(c, room)
+	}
+}
+
+// Clients returns the connections of userID on this instance.
+func (h *Hub) Clients(userID string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return collect(h.users[userID])
+}
+
+// IsOnline reports whether userID has at least one connection on this instance.
+func (h *Hub) IsOnline(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.users[userID]) > 0
+}
+
+// IsOnlineAnywhere reports whether userID has a connection on this instance or, as far as the presence
+// shared over the backplane tells, on another one.
+func (h *Hub) IsOnlineAnywhere(userID string) bool {
+	return h.IsOnline(userID) || len(h.instancesWith(userKey(userID))) > 0
+}
+
+// Disconnect closes every connection of userID with code and text, here and on the instances holding its
+// other connections when a backplane is in use.
+func (h *Hub) Disconnect(userID string, code int, text string) {
+	h.route(userKey(userID), BackplaneMessage{Kind: KindDisconnect, Target: userID, Code: code, Text: text})
+	h.disconnect(userID, code, text)
+}
+
+func (h *Hub) disconnect(userID string, code int, text string) {
+	for _, c := range h.Clients(userID) {
+		c.Close(code, text)
+	}
+}
+
+// Shutdown stops accepting connections, closes the open ones with CloseGoingAway and waits for them
+// to finish until ctx is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closed = true
+	var clients []*Client
+	for _, conns := range h.users {
+		clients = append(clients, collect(conns)...)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		c.Close(websocket.CloseGoingAway, "Server shutting down")
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		log.Println("✅ WebSocket hub closed")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) sendBuffer() int {
+	if h.SendBuffer <= 0 {
+		return DefaultSendBuffer
+	}
+	return h.SendBuffer
+}
+
+func (h *Hub) pongWait() time.Duration {
+	if h.PongWait <= 0 {
+		return DefaultPongWait
+	}
+	return h.PongWait
+}
+
+func (h *Hub) pingInterval() time.Duration {
+	if h.PingInterval <= 0 || h.PingInterval >= h.pongWait() {
+		return h.pongWait() * 9 / 10
+	}
+	return h.PingInterval
+}
+
+func (h *Hub) writeWait() time.Duration {
+	if h.WriteWait <= 0 {
+		return DefaultWriteWait
+	}
+	return h.WriteWait
+}
+
+func (h *Hub) backplaneBuffer() int {
+	if h.BackplaneBuffer <= 0 {
+		return DefaultBackplaneBuffer
+	}
+	return h.BackplaneBuffer
+}
+
+func (h *Hub) presenceInterval() time.Duration {
+	if h.PresenceInterval <= 0 {
+		return DefaultPresenceInterval
+	}
+	return h.PresenceInterval
+}
+
+func (h *Hub) maxMessageSize() int64 {
+	if h.MaxMessageSize <= 0 {
+		return DefaultMaxMessageSize
+	}
+	return h.MaxMessageSize
+}
+
+func collect(set map[*Client]bool) []*Client {
+	clients := make([]*Client, 0, len(set))
+	for c := range set {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+func send(clients []*Client, message []byte) int {
+	sent := 0
+	for _, c := range clients {
+		if c.Send(message) {
+			sent++
+		}
+	}
+	return sent
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/websocket/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenSubprotocol lets browsers, which cannot set headers on a WebSocket, send the token as a subprotocol:
+// new WebSocket(url, ["access_token", token]). The server must accept it with
+// websocket.New(handler, websocket.Config{Subprotocols: []string{config.TokenSubprotocol}}).
+const TokenSubprotocol = "access_token"
+
+// ErrMissingToken is returned when a connection provides no token in time.
+var ErrMissingToken = errors.New("no token provided")
+
+// AuthConfig controls how ServeAuthenticated finds and checks the token of a connection. The token is
+// looked up in the QueryParam, then the Authorization header, then the TokenSubprotocol, and finally read
+// from the first message, either as {"token": "..."}, an "auth" Envelope or the raw token.
+type AuthConfig struct {
+	QueryParam       string                              // Defaults to "token"
+	HandshakeTimeout time.Duration                       // Time allowed for the first message, defaults to 10s
+	Roles            func(claims jwt.MapClaims) []string // Defaults to the "role" claim and the roles GenerateJWT issues
+}
+
+// DefaultAuthConfig is the AuthConfig used by WebSocketHandler.
+var DefaultAuthConfig = AuthConfig{QueryParam: "token", HandshakeTimeout: 10 * time.Second}
+
+// ServeAuthenticated validates the token of conn with utils.ValidateToken and serves it for the user in its
+// claims. The connection is closed when the token expires or is revoked with utils.DeleteToken.
+func (h *Hub) ServeAuthenticated(conn *websocket.Conn, cfg AuthConfig) error {
+	token, claims, err := Authenticate(conn, cfg)
+	if err != nil {
+		reject(conn, "Unauthorized", h.writeWait())
+		return err
+	}
+
+	userID := utils.ActorFromClaims(claims)
+	if userID == "" {
+		reject(conn, "Unauthorized", h.writeWait())
+		return errors.New("token has no user_id")
+	}
+	if id := conn.Params("id"); id != "" && id != userID {
+		reject(conn, "Forbidden", h.writeWait())
+		return fmt.Errorf("token of user %s used to connect as user %s", userID, id)
+	}
+
+	h.watchRevoked.Do(func() {
+		utils.OnTokenRevoked(h.revoke)
+	})
+
+	c := h.newClient(conn, userID)
+	c.Claims = claims
+	c.Roles = cfg.roles(claims)
+	c.token = token
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiry := time.AfterFunc(time.Until(exp.Time), func() {
+			c.Close(websocket.ClosePolicyViolation, "Token expired")
+		})
+		defer expiry.Stop()
+	}
+
+	return h.serve(c)
+}
+
+// Authenticate finds the token of conn as described in AuthConfig and validates it.
+func Authenticate(conn *websocket.Conn, cfg AuthConfig) (string, jwt.MapClaims, error) {
+	token := tokenFromRequest(conn, cfg)
+	if token == "" {
+		var err error
+		if token, err = tokenFromMessage(conn, cfg); err != nil {
+			return "", nil, err
+		}
+	}
+
+	claims, err := utils.ValidateToken(token)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+func tokenFromRequest(conn *websocket.Conn, cfg AuthConfig) string {
+	param := cfg.QueryParam
+	if param == "" {
+		param = "token"
+	}
+	if token := conn.Query(param); token != "" {
+		return token
+	}
+
+	if auth := conn.Headers("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+
+	// Sec-WebSocket-Protocol: access_token, <token>
+	protocols := strings.Split(conn.Headers("Sec-Websocket-Protocol"), ",")
+	for i := 0; i < len(protocols)-1; i++ {
+		if strings.TrimSpace(protocols[i]) == TokenSubprotocol {
+			return strings.TrimSpace(protocols[i+1])
+		}
+	}
+	return ""
+}
+
+func tokenFromMessage(conn *websocket.Conn, cfg AuthConfig) (string, error) {
+	timeout := cfg.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMissingToken, err)
+	}
+
+	// {"token": "..."} or an envelope {"type": "auth", "payload": {"token": "..."}}
+	var auth struct {
+		Token   string `json:"token"`
+		Payload struct {
+			Token string `json:"token"`
+		} `json:"payload"`
+	}
+	if json.Unmarshal(message, &auth) == nil {
+		if auth.Token != "" {
+			return auth.Token, nil
+		}
+		if auth.Payload.Token != "" {
+			return auth.Payload.Token, nil
+		}
+	}
+	if token := strings.TrimSpace(string(message)); token != "" && !strings.HasPrefix(token, "{") {
+		return token, nil
+	}
+	return "", ErrMissingToken
+}
+
+func (cfg AuthConfig) roles(claims jwt.MapClaims) []string {
+	if cfg.Roles != nil {
+		return cfg.Roles(claims)
+	}
+
+	var roles []string
+	if role, ok := claims["role"].(string); ok && role != "" {
+		roles = append(roles, role)
+	}
+	if list, ok := claims[utils.ClaimRoles].([]interface{}); ok {
+		for _, role := range list {
+			if role, ok := role.(string); ok && role != "" {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// revoke closes the connections authenticated with token.
+func (h *Hub) revoke(token string) {
+	h.mu.RLock()
+	clients := collect(h.tokens[token])
+	h.mu.RUnlock()
+
+	for _, c := range clients {
+		c.Close(websocket.ClosePolicyViolation, "Token revoked")
+	}
+}
+
+func reject(conn *websocket.Conn, text string, wait time.Duration) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, text), time.Now().Add(wait))
+	conn.Close()
+}
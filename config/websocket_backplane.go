@@ -0,0 +1,507 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Backplane message kinds
+const (
+	KindUser       = "user"
+	KindRoom       = "room"
+	KindBroadcast  = "broadcast"
+	KindDisconnect = "disconnect"
+	KindAck        = "ack"
+	KindPresence   = "presence" // A user or room got its first, or lost its last, connection on Origin
+	KindSnapshot   = "snapshot" // Every user and room with a connection on Origin
+	KindSync       = "sync"     // Asks every instance for a snapshot
+)
+
+// Hub backplane defaults
+const (
+	DefaultBackplaneBuffer  = 1024
+	DefaultPresenceInterval = 30 * time.Second
+)
+
+// BackplaneMessage is a hub operation forwarded to the other instances.
+type BackplaneMessage struct {
+	Origin  string `json:"origin"`       // InstanceID of the publishing hub
+	To      string `json:"to,omitempty"` // InstanceID of the only hub receiving it, every hub when empty
+	Kind    string `json:"kind"`
+	Target  string `json:"target,omitempty"` // User ID, room or acknowledged event ID
+	Payload []byte `json:"payload,omitempty"`
+	Ack     string `json:"ack,omitempty"`  // Event ID the receiving connections may acknowledge, for KindUser and KindRoom
+	Code    int    `json:"code,omitempty"` // Close code of KindDisconnect
+	Text    string `json:"text,omitempty"` // Close text of KindDisconnect, acknowledging user of KindAck
+}
+
+// Backplane carries hub messages between the instances of the application, so that a message reaches
+// a user whichever instance holds their connections.
+type Backplane interface {
+	Publish(ctx context.Context, msg BackplaneMessage) error
+	// Subscribe delivers the messages published to every instance, including this instance's own, and
+	// those sent to instanceID, to handler until ctx is done. It returns once the subscription is active.
+	Subscribe(ctx context.Context, instanceID string, handler func(BackplaneMessage)) error
+}
+
+// ConnectWebSocketBackplane fans DefaultHub out across instances over PgxPool. Call it after PostgreSQLConnect.
+func ConnectWebSocketBackplane(ctx context.Context) {
+	if err := DefaultHub.UseBackplane(ctx, NewPgBackplane(PgxPool)); err != nil {
+		log.Fatalf("❌ Failed to start WebSocket backplane: %v", err)
+	}
+	log.Println("✅ WebSocket backplane listening")
+}
+
+// UseBackplane forwards the sends of h to the other instances through bp and delivers theirs to the
+// local connections, until ctx is done. Instances share which users and rooms they hold connections of,
+// so messages only go to the instances that can deliver them.
+func (h *Hub) UseBackplane(ctx context.Context, bp Backplane) error {
+	if err := bp.Subscribe(ctx, h.InstanceID, h.receive); err != nil {
+		return fmt.Errorf("failed to subscribe to backplane: %w", err)
+	}
+
+	queue := make(chan BackplaneMessage, h.backplaneBuffer())
+	h.mu.Lock()
+	h.backplane = bp
+	h.outbox = queue
+	h.mu.Unlock()
+
+	go h.publishLoop(ctx, bp, queue)
+	go h.presenceLoop(ctx)
+	return nil
+}
+
+// publishLoop publishes the queued messages in order until ctx is done, so senders never wait on the backplane.
+func (h *Hub) publishLoop(ctx context.Context, bp Backplane, queue chan BackplaneMessage) {
+	defer func() {
+		h.mu.Lock()
+		if h.backplane == bp {
+			h.backplane = nil
+			h.outbox = nil
+		}
+		h.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-queue:
+			publishCtx, cancel := context.WithTimeout(ctx, h.writeWait())
+			if err := bp.Publish(publishCtx, msg); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️ Failed to forward WebSocket %s message: %v", msg.Kind, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// forward queues msg for the backplane, if one is in use. It never blocks: when the queue is full the
+// message is dropped.
+func (h *Hub) forward(msg BackplaneMessage) {
+	h.mu.RLock()
+	queue := h.outbox
+	h.mu.RUnlock()
+	if queue == nil {
+		return
+	}
+
+	msg.Origin = h.InstanceID
+	select {
+	case queue <- msg:
+	default:
+		log.Printf("⚠️ WebSocket backplane queue full, dropping %s message", msg.Kind)
+	}
+}
+
+// route forwards msg to each other instance holding a connection of key.
+func (h *Hub) route(key string, msg BackplaneMessage) {
+	for _, instanceID := range h.instancesWith(key) {
+		msg.To = instanceID
+		h.forward(msg)
+	}
+}
+
+// receive delivers a message published by another instance to the local connections.
+func (h *Hub) receive(msg BackplaneMessage) {
+	if msg.Origin == h.InstanceID || (msg.To != "" && msg.To != h.InstanceID) {
+		return
+	}
+
+	switch msg.Kind {
+	case KindUser:
+		if msg.Ack != "" {
+			h.sendAck(h.Clients(msg.Target), msg.Payload, msg.Ack, msg.Origin)
+		} else {
+			h.sendToUser(msg.Target, msg.Payload)
+		}
+	case KindRoom:
+		if msg.Ack != "" {
+			h.sendAck(h.members(msg.Target), msg.Payload, msg.Ack, msg.Origin)
+		} else {
+			h.publish(msg.Target, msg.Payload)
+		}
+	case KindBroadcast:
+		h.broadcast(msg.Payload)
+	case KindDisconnect:
+		h.disconnect(msg.Target, msg.Code, msg.Text)
+	case KindAck:
+		h.ack(msg.Target, msg.Text)
+	case KindPresence:
+		h.updatePresence(msg.Origin, func(keys map[string]bool) {
+			if msg.Code == 1 {
+				keys[msg.Target] = true
+			} else {
+				delete(keys, msg.Target)
+			}
+		})
+	case KindSnapshot:
+		var list []string
+		if err := json.Unmarshal(msg.Payload, &list); err != nil {
+			log.Printf("⚠️ Invalid WebSocket presence snapshot from %s: %v", msg.Origin, err)
+			return
+		}
+		h.updatePresence(msg.Origin, func(keys map[string]bool) {
+			clear(keys)
+			for _, key := range list {
+				keys[key] = true
+			}
+		})
+	case KindSync:
+		h.sendSnapshot(msg.Origin)
+	default:
+		log.Printf("⚠️ Unknown WebSocket backplane message kind %q", msg.Kind)
+	}
+}
+
+// instancePresence is what a hub knows of the connections of another instance
+type instancePresence struct {
+	keys map[string]bool // userKey and roomKey of its connections
+	seen time.Time
+}
+
+func userKey(userID string) string {
+	return KindUser + ":" + userID
+}
+
+func roomKey(room string) string {
+	return KindRoom + ":" + room
+}
+
+// presenceLoop asks the other instances for their connections, then shares this instance's own every
+// PresenceInterval, forgetting instances not heard of for three intervals. The snapshots repair
+// whatever presence change was lost, e.g. while the backplane reconnected.
+func (h *Hub) presenceLoop(ctx context.Context) {
+	h.forward(BackplaneMessage{Kind: KindSync})
+	h.sendSnapshot("")
+
+	ticker := time.NewTicker(h.presenceInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.presenceMu.Lock()
+			h.presence = nil
+			h.presenceMu.Unlock()
+			return
+		case <-ticker.C:
+			h.sendSnapshot("")
+
+			expired := time.Now().Add(-3 * h.presenceInterval())
+			h.presenceMu.Lock()
+			for instanceID, p := range h.presence {
+				if p.seen.Before(expired) {
+					delete(h.presence, instanceID)
+				}
+			}
+			h.presenceMu.Unlock()
+		}
+	}
+}
+
+// sendSnapshot publishes the users and rooms with a local connection to instanceID, or to every instance.
+func (h *Hub) sendSnapshot(instanceID string) {
+	h.mu.RLock()
+	keys := make([]string, 0, len(h.users)+len(h.rooms))
+	for userID := range h.users {
+		keys = append(keys, userKey(userID))
+	}
+	for room := range h.rooms {
+		keys = append(keys, roomKey(room))
+	}
+	h.mu.RUnlock()
+
+	payload, _ := json.Marshal(keys)
+	h.forward(BackplaneMessage{Kind: KindSnapshot, To: instanceID, Payload: payload})
+}
+
+// announce tells the other instances that key got its first, or lost its last, local connection.
+func (h *Hub) announce(key string, present bool) {
+	msg := BackplaneMessage{Kind: KindPresence, Target: key}
+	if present {
+		msg.Code = 1
+	}
+	h.forward(msg)
+}
+
+func (h *Hub) updatePresence(instanceID string, update func(keys map[string]bool)) {
+	h.presenceMu.Lock()
+	defer h.presenceMu.Unlock()
+	if h.presence == nil {
+		h.presence = make(map[string]*instancePresence)
+	}
+	p := h.presence[instanceID]
+	if p == nil {
+		p = &instancePresence{keys: make(map[string]bool)}
+		h.presence[instanceID] = p
+	}
+	p.seen = time.Now()
+	update(p.keys)
+}
+
+// instancesWith returns the other live instances holding a connection of key.
+func (h *Hub) instancesWith(key string) []string {
+	h.presenceMu.RLock()
+	defer h.presenceMu.RUnlock()
+
+	expired := time.Now().Add(-3 * h.presenceInterval())
+	var instances []string
+	for instanceID, p := range h.presence {
+		if p.keys[key] && p.seen.After(expired) {
+			instances = append(instances, instanceID)
+		}
+	}
+	return instances
+}
+
+// MemoryBackplane is an in-process Backplane for single-node deployments and tests.
+type MemoryBackplane struct {
+	mu       sync.RWMutex
+	handlers map[int]memorySubscriber
+	next     int
+}
+
+type memorySubscriber struct {
+	instanceID string
+	handler    func(BackplaneMessage)
+}
+
+// NewMemoryBackplane returns a MemoryBackplane without subscribers.
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{handlers: make(map[int]memorySubscriber)}
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, msg BackplaneMessage) error {
+	b.mu.RLock()
+	handlers := make([]func(BackplaneMessage), 0, len(b.handlers))
+	for _, sub := range b.handlers {
+		if msg.To == "" || msg.To == sub.instanceID {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context, instanceID string, handler func(BackplaneMessage)) error {
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.handlers[id] = memorySubscriber{instanceID: instanceID, handler: handler}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}()
+	return nil
+}
+
+// PgBackplane defaults
+const (
+	DefaultBackplaneChannel = "websocket_hub"
+	DefaultOverflowTTL      = 5 * time.Minute
+
+	// PostgreSQL rejects NOTIFY payloads of 8000 bytes or more
+	maxNotifyPayload = 7999
+)
+
+// PgBackplane is a Backplane over PostgreSQL LISTEN/NOTIFY. Messages to every instance are notified on
+// Channel, those to one instance on Channel followed by an underscore and its ID. Messages too large for
+// a NOTIFY payload are stored in sharedModels.WebSocketOverflow and only their ID is notified.
+type PgBackplane struct {
+	Pool        *pgxpool.Pool
+	Channel     string
+	OverflowTTL time.Duration // How long overflow rows are kept for slow listeners
+}
+
+// NewPgBackplane returns a PgBackplane on pool using the default channel.
+func NewPgBackplane(pool *pgxpool.Pool) *PgBackplane {
+	return &PgBackplane{Pool: pool, Channel: DefaultBackplaneChannel, OverflowTTL: DefaultOverflowTTL}
+}
+
+// overflowRef is notified in place of a message stored in the overflow table.
+type overflowRef struct {
+	Ref int64 `json:"ref"`
+}
+
+func (b *PgBackplane) Publish(ctx context.Context, msg BackplaneMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxNotifyPayload {
+		table := sharedModels.WebSocketOverflow{}.TableName()
+		var id int64
+		err := b.Pool.QueryRow(ctx, `INSERT INTO `+table+` (payload, created_at) VALUES ($1, now()) RETURNING id`, data).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to store large backplane message: %w", err)
+		}
+		if _, err := b.Pool.Exec(ctx, `DELETE FROM `+table+` WHERE created_at < $1`, time.Now().Add(-b.overflowTTL())); err != nil {
+			log.Printf("⚠️ Failed to clean up WebSocket overflow: %v", err)
+		}
+		data, _ = json.Marshal(overflowRef{Ref: id})
+	}
+
+	channel := b.channel()
+	if msg.To != "" {
+		channel = b.instanceChannel(msg.To)
+	}
+	_, err = b.Pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(data))
+	return err
+}
+
+func (b *PgBackplane) Subscribe(ctx context.Context, instanceID string, handler func(BackplaneMessage)) error {
+	channels := []string{b.channel(), b.instanceChannel(instanceID)}
+	conn, err := b.listen(ctx, channels)
+	if err != nil {
+		return err
+	}
+	go b.run(ctx, conn, channels, handler)
+	return nil
+}
+
+// listen takes a connection out of the pool and starts listening on channels. The connection
+// is never returned to the pool, as it stays subscribed.
+func (b *PgBackplane) listen(ctx context.Context, channels []string) (*pgx.Conn, error) {
+	pooled, err := b.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := pooled.Hijack()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			conn.Close(context.Background())
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// run waits for notifications until ctx is done, reconnecting when the connection is lost.
+func (b *PgBackplane) run(ctx context.Context, conn *pgx.Conn, channels []string, handler func(BackplaneMessage)) {
+	defer func() {
+		if conn != nil {
+			conn.Close(context.Background())
+		}
+	}()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			conn.Close(context.Background())
+			conn = nil
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("⚠️ WebSocket backplane connection lost: %v", err)
+			if conn = b.reconnect(ctx, channels); conn == nil {
+				return
+			}
+			continue
+		}
+
+		msg, err := b.decode(ctx, notification.Payload)
+		if err != nil {
+			log.Printf("⚠️ Invalid WebSocket backplane message: %v", err)
+			continue
+		}
+		handler(msg)
+	}
+}
+
+func (b *PgBackplane) reconnect(ctx context.Context, channels []string) *pgx.Conn {
+	delay := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := b.listen(ctx, channels)
+		if err == nil {
+			log.Println("✅ WebSocket backplane reconnected")
+			return conn
+		}
+		log.Printf("⚠️ WebSocket backplane reconnect failed: %v", err)
+		if delay < 30*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+func (b *PgBackplane) decode(ctx context.Context, payload string) (BackplaneMessage, error) {
+	var msg BackplaneMessage
+	data := []byte(payload)
+
+	var ref overflowRef
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return msg, err
+	}
+	if ref.Ref > 0 {
+		table := sharedModels.WebSocketOverflow{}.TableName()
+		if err := b.Pool.QueryRow(ctx, `SELECT payload FROM `+table+` WHERE id = $1`, ref.Ref).Scan(&data); err != nil {
+			return msg, fmt.Errorf("failed to load overflow message %d: %w", ref.Ref, err)
+		}
+	}
+
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+func (b *PgBackplane) channel() string {
+	if b.Channel == "" {
+		return DefaultBackplaneChannel
+	}
+	return b.Channel
+}
+
+// instanceChannel is the channel of the messages to instanceID only
+func (b *PgBackplane) instanceChannel(instanceID string) string {
+	return b.channel() + "_" + instanceID
+}
+
+func (b *PgBackplane) overflowTTL() time.Duration {
+	if b.OverflowTTL <= 0 {
+		return DefaultOverflowTTL
+	}
+	return b.OverflowTTL
+}
@@ -0,0 +1,393 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/DevdotSP/go-utils/utils"
+)
+
+// Message types handled by the Router itself
+const (
+	TypeReply       = "reply"       // Server answer to a client request, with the request ID
+	TypeError       = "error"       // Protocol or handler error, with the request ID when there was one
+	TypeAck         = "ack"         // Client acknowledgement of a server event sent with Ack set
+	TypeSubscribe   = "subscribe"   // Join the room in Topic
+	TypeUnsubscribe = "unsubscribe" // Leave the room in Topic
+)
+
+// Envelope is the JSON frame exchanged over hub connections. A client message with an ID is a request
+// and always gets a reply or an error with the same ID. RetCode and Message use the respcode vocabulary.
+type Envelope struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Topic   string          `json:"topic,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Ack     bool            `json:"ack,omitempty"` // The client must answer with an ack of the same ID
+	RetCode string          `json:"retCode,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// NewEnvelope returns an envelope of msgType on topic carrying payload encoded as JSON.
+func NewEnvelope(msgType, topic string, payload interface{}) (Envelope, error) {
+	env := Envelope{Type: msgType, Topic: topic}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return env, err
+		}
+		env.Payload = data
+	}
+	return env, nil
+}
+
+// Decode unmarshals the payload of env into v.
+func (env Envelope) Decode(v interface{}) error {
+	if len(env.Payload) == 0 {
+		return NewProtocolError(respcode.ERR_CODE_400, "Payload is required")
+	}
+	if err := json.Unmarshal(env.Payload, v); err != nil {
+		return NewProtocolError(respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG)
+	}
+	return nil
+}
+
+// ProtocolError is returned by message handlers to answer with a specific respcode code.
+type ProtocolError struct {
+	RetCode string
+	Message string
+}
+
+// NewProtocolError returns a ProtocolError with retCode and message.
+func NewProtocolError(retCode, message string) *ProtocolError {
+	return &ProtocolError{RetCode: retCode, Message: message}
+}
+
+func (e *ProtocolError) Error() string {
+	return e.RetCode + ": " + e.Message
+}
+
+// MessageHandler handles a client message. Its result is sent back as the reply payload when the message
+// is a request; any error that is not a ProtocolError is reported as a 500.
+type MessageHandler func(c *Client, env Envelope) (interface{}, error)
+
+// Router dispatches the envelopes received by a hub to the handler registered for their type.
+// Handlers run on the reading goroutine of the connection, so messages of a connection are handled in order.
+type Router struct {
+	hub      *Hub
+	mu       sync.RWMutex
+	handlers map[string]MessageHandler
+}
+
+// NewRouter returns a Router for h and installs it as h.OnMessage. Acks and subscribe/unsubscribe,
+// which honours the RoomRoles of h, are handled out of the box.
+func NewRouter(h *Hub) *Router {
+	r := &Router{hub: h, handlers: make(map[string]MessageHandler)}
+	r.Handle(TypeSubscribe, func(c *Client, env Envelope) (interface{}, error) {
+		if env.Topic == "" {
+			return nil, NewProtocolError(respcode.ERR_CODE_400, "Topic is required")
+		}
+		if err := c.Join(env.Topic); err != nil {
+			return nil, NewProtocolError(respcode.ERR_CODE_403, respcode.ERR_CODE_403_MSG)
+		}
+		return nil, nil
+	})
+	r.Handle(TypeUnsubscribe, func(c *Client, env Envelope) (interface{}, error) {
+		c.Leave(env.Topic)
+		return nil, nil
+	})
+	h.OnMessage = r.Dispatch
+	return r
+}
+
+// Handle registers fn for messages of msgType, replacing any previous handler.
+func (r *Router) Handle(msgType string, fn MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[msgType] = fn
+}
+
+// Dispatch decodes message and runs its handler, answering requests with a reply or an error.
+func (r *Router) Dispatch(c *Client, message []byte) {
+	var env Envelope
+	if err := json.Unmarshal(message, &env); err != nil || env.Type == "" {
+		c.SendEnvelope(errorEnvelope("", NewProtocolError(respcode.ERR_CODE_400, "Invalid message")))
+		return
+	}
+
+	if env.Type == TypeAck {
+		if env.ID != "" {
+			r.hub.acknowledge(c, env.ID)
+		}
+		return
+	}
+
+	r.mu.RLock()
+	handler, ok := r.handlers[env.Type]
+	r.mu.RUnlock()
+	if !ok {
+		c.SendEnvelope(errorEnvelope(env.ID, NewProtocolError(respcode.ERR_CODE_404, "Unknown message type "+env.Type)))
+		return
+	}
+
+	result, err := handler(c, env)
+	if err != nil {
+		c.SendEnvelope(errorEnvelope(env.ID, err))
+		return
+	}
+	if env.ID == "" {
+		return
+	}
+
+	reply, err := NewEnvelope(TypeReply, env.Topic, result)
+	if err != nil {
+		c.SendEnvelope(errorEnvelope(env.ID, err))
+		return
+	}
+	reply.ID = env.ID
+	reply.RetCode = respcode.SUC_CODE_200
+	reply.Message = respcode.SUC_CODE_200_MSG
+	c.SendEnvelope(reply)
+}
+
+// errorEnvelope answers with a ProtocolError as is. Other errors are logged and answered with a
+// generic 500, so internal details never reach the client.
+func errorEnvelope(id string, err error) Envelope {
+	env := Envelope{Type: TypeError, ID: id}
+
+	var protocolErr *ProtocolError
+	if errors.As(err, &protocolErr) {
+		env.RetCode = protocolErr.RetCode
+		env.Message = protocolErr.Message
+		return env
+	}
+	log.Printf("❌ WebSocket message %q failed: %v", id, err)
+	env.RetCode = respcode.ERR_CODE_500
+	env.Message = respcode.ERR_CODE_500_MSG
+	return env
+}
+
+// SendEnvelope queues env for this connection.
+func (c *Client) SendEnvelope(env Envelope) bool {
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode WebSocket %s message: %v", env.Type, err)
+		return false
+	}
+	return c.Send(data)
+}
+
+// SendEnvelope sends env to every connection of userID, see SendToUser.
+func (h *Hub) SendEnvelope(userID string, env Envelope) (int, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return 0, err
+	}
+	return h.SendToUser(userID, data), nil
+}
+
+// PublishEnvelope sends env to every connection in room, see Publish.
+func (h *Hub) PublishEnvelope(room string, env Envelope) (int, error) {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return 0, err
+	}
+	return h.Publish(room, data), nil
+}
+
+// AckPolicy controls how long DeliverToUser waits for an ack and how often it resends.
+type AckPolicy struct {
+	Timeout  time.Duration // Wait per attempt, defaults to 5s
+	Attempts int           // Defaults to 3
+}
+
+func (p AckPolicy) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return 5 * time.Second
+	}
+	return p.Timeout
+}
+
+func (p AckPolicy) attempts() int {
+	if p.Attempts <= 0 {
+		return 3
+	}
+	return p.Attempts
+}
+
+// ackWaiter collects the users that acknowledged one event.
+type ackWaiter struct {
+	target string // The only user allowed to ack, empty for rooms
+	mu     sync.Mutex
+	users  map[string]bool
+	acked  chan struct{} // Closed on the first ack
+}
+
+// Events a connection may acknowledge
+const (
+	maxPendingAcks = 256
+	pendingAckTTL  = 2 * time.Minute
+)
+
+// pendingAck is an event sent to a connection with Ack set
+type pendingAck struct {
+	origin  string // InstanceID of the hub waiting for the ack
+	expires time.Time
+}
+
+// DeliverToUser sends env to every connection of userID and waits until one of them acknowledges it,
+// resending after each timeout. Clients must ignore duplicate IDs. It reports whether the event was acknowledged.
+func (h *Hub) DeliverToUser(ctx context.Context, userID string, env Envelope, policy AckPolicy) (bool, error) {
+	data, w, err := h.prepareAck(&env, userID)
+	if err != nil {
+		return false, err
+	}
+	defer h.releaseAck(env.ID)
+
+	for attempt := 0; attempt < policy.attempts(); attempt++ {
+		// Nobody to wait for when no instance holds a connection of the user
+		if h.sendToUserAck(userID, data, env.ID) == 0 && len(h.instancesWith(userKey(userID))) == 0 {
+			return false, nil
+		}
+
+		timer := time.NewTimer(policy.timeout())
+		select {
+		case <-w.acked:
+			timer.Stop()
+			return true, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return false, nil
+}
+
+// DeliverToRoom publishes env to room once and returns the users that acknowledged it within wait.
+func (h *Hub) DeliverToRoom(ctx context.Context, room string, env Envelope, wait time.Duration) ([]string, error) {
+	data, w, err := h.prepareAck(&env, "")
+	if err != nil {
+		return nil, err
+	}
+	defer h.releaseAck(env.ID)
+
+	if h.publishAck(room, data, env.ID) > 0 || len(h.instancesWith(roomKey(room))) > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	users := make([]string, 0, len(w.users))
+	for userID := range w.users {
+		users = append(users, userID)
+	}
+	return users, err
+}
+
+func (h *Hub) prepareAck(env *Envelope, target string) ([]byte, *ackWaiter, error) {
+	env.Ack = true
+	if env.ID == "" {
+		env.ID = utils.GenerateUUID()
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &ackWaiter{target: target, users: make(map[string]bool), acked: make(chan struct{})}
+	h.ackMu.Lock()
+	if h.acks == nil {
+		h.acks = make(map[string]*ackWaiter)
+	}
+	h.acks[env.ID] = w
+	h.ackMu.Unlock()
+	return data, w, nil
+}
+
+func (h *Hub) releaseAck(id string) {
+	h.ackMu.Lock()
+	delete(h.acks, id)
+	h.ackMu.Unlock()
+}
+
+// sendToUserAck is SendToUser for an event awaiting acks: the connections receiving it may then ack id.
+func (h *Hub) sendToUserAck(userID string, message []byte, id string) int {
+	h.route(userKey(userID), BackplaneMessage{Kind: KindUser, Target: userID, Payload: message, Ack: id})
+	return h.sendAck(h.Clients(userID), message, id, h.InstanceID)
+}
+
+// publishAck is Publish for an event awaiting acks: the connections receiving it may then ack id.
+func (h *Hub) publishAck(room string, message []byte, id string) int {
+	h.route(roomKey(room), BackplaneMessage{Kind: KindRoom, Target: room, Payload: message, Ack: id})
+	return h.sendAck(h.members(room), message, id, h.InstanceID)
+}
+
+// sendAck queues message for clients and records that they may ack id to the hub origin.
+func (h *Hub) sendAck(clients []*Client, message []byte, id, origin string) int {
+	now := time.Now()
+	h.ackMu.Lock()
+	for _, c := range clients {
+		if c.acks == nil {
+			c.acks = make(map[string]pendingAck)
+		}
+		if len(c.acks) >= maxPendingAcks {
+			for pendingID, pending := range c.acks {
+				if now.After(pending.expires) {
+					delete(c.acks, pendingID)
+				}
+			}
+		}
+		if len(c.acks) < maxPendingAcks {
+			c.acks[id] = pendingAck{origin: origin, expires: now.Add(pendingAckTTL)}
+		}
+	}
+	h.ackMu.Unlock()
+	return send(clients, message)
+}
+
+// acknowledge records the ack of c for event id and forwards it to the instance waiting for it. Acks of
+// events that were not sent to c are dropped.
+func (h *Hub) acknowledge(c *Client, id string) {
+	h.ackMu.Lock()
+	pending, ok := c.acks[id]
+	delete(c.acks, id)
+	h.ackMu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return
+	}
+
+	if pending.origin == h.InstanceID {
+		h.ack(id, c.UserID)
+		return
+	}
+	h.forward(BackplaneMessage{Kind: KindAck, To: pending.origin, Target: id, Text: c.UserID})
+}
+
+func (h *Hub) ack(id, userID string) bool {
+	h.ackMu.Lock()
+	w := h.acks[id]
+	h.ackMu.Unlock()
+	if w == nil || (w.target != "" && w.target != userID) {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.users) == 0 {
+		close(w.acked)
+	}
+	w.users[userID] = true
+	return true
+}
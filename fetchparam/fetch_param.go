@@ -7,6 +7,9 @@ import (
 )
 
 // FetchParam fetches parameters dynamically from a given database connection.
+// Only the first matching row is returned.
+//
+// Deprecated: use sysparam.Service for the system parameters, which is cached and typed.
 func FetchParam(db *gorm.DB, tableName, columnName, columnValue string, columnToSelect []string) (map[string]interface{}, error) {
 	var results []map[string]interface{}
 
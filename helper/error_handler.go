@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ErrorHandler maps the errors returned by handlers to responses, e.g. fiber.Config{ErrorHandler: helper.ErrorHandler}.
+// Calls rejected by a circuit breaker, rate limit or concurrency limit give ERR_CODE_503 and failed
+// upstream calls of utils.ApiRequest ERR_CODE_502. Unlike handler responses, the HTTP status is also set, to the RetCode.
+func ErrorHandler(c fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	var httpErr *utils.HTTPError
+	var upstreamErr *utils.UpstreamError
+
+	switch {
+	case errors.Is(err, utils.ErrCircuitOpen), errors.Is(err, utils.ErrRateLimited), errors.Is(err, utils.ErrBulkheadFull):
+		var resErr *utils.ResilienceError
+		if errors.As(err, &resErr) && resErr.RetryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(resErr.RetryAfter.Seconds()))))
+		}
+		c.Status(fiber.StatusServiceUnavailable)
+		return JSONResponseWithError(c, respcode.ERR_CODE_503, respcode.ERR_CODE_503_MSG, err)
+	case errors.As(err, &httpErr), errors.As(err, &upstreamErr):
+		c.Status(fiber.StatusBadGateway)
+		return JSONResponseWithError(c, respcode.ERR_CODE_502, respcode.ERR_CODE_502_MSG, err)
+	case errors.As(err, &fiberErr):
+		c.Status(fiberErr.Code)
+		return JSONResponse(c, strconv.Itoa(fiberErr.Code), fiberErr.Message)
+	default:
+		c.Status(fiber.StatusInternalServerError)
+		return JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+}
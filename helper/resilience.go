@@ -0,0 +1,12 @@
+package helper
+
+import (
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ResilienceStatsHandler returns the circuit breaker state and counters of every host called through utils.DefaultResilience.
+func ResilienceStatsHandler(c fiber.Ctx) error {
+	return JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, utils.DefaultResilience.Stats())
+}
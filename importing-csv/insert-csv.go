@@ -8,6 +8,8 @@ import (
 
 	"github.com/DevdotSP/go-utils/config"
 	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/tenant"
+	"gorm.io/gorm"
 )
 
 const batchSize = 500
@@ -25,7 +27,7 @@ func loadCSV(filePath string) ([][]string, error) {
 	return reader.ReadAll()
 }
 
-func importRegions() {
+func importRegions(db *gorm.DB) {
 	records, err := loadCSV("csv-file/dataregion.csv")
 	if err != nil {
 		log.Fatal("Error reading region CSV:", err)
@@ -43,14 +45,14 @@ func importRegions() {
 		})
 	}
 
-	if err := config.DB.CreateInBatches(&regions, batchSize).Error; err != nil {
+	if err := db.CreateInBatches(&regions, batchSize).Error; err != nil {
 		log.Fatal("Error inserting regions:", err)
 	}
 
 	fmt.Println("✅ Regions imported successfully")
 }
 
-func importProvinces() {
+func importProvinces(db *gorm.DB) {
 	records, err := loadCSV("csv-file/dataprovince.csv")
 	if err != nil {
 		log.Fatal("Error reading province CSV:", err)
@@ -69,14 +71,14 @@ func importProvinces() {
 		})
 	}
 
-	if err := config.DB.CreateInBatches(&provinces, batchSize).Error; err != nil {
+	if err := db.CreateInBatches(&provinces, batchSize).Error; err != nil {
 		log.Fatal("Error inserting provinces:", err)
 	}
 
 	fmt.Println("✅ Provinces imported successfully")
 }
 
-func importMunicipalities() {
+func importMunicipalities(db *gorm.DB) {
 	records, err := loadCSV("csv-file/datamunicipality.csv")
 	if err != nil {
 		log.Fatal("Error reading municipality CSV:", err)
@@ -95,14 +97,14 @@ func importMunicipalities() {
 		})
 	}
 
-	if err := config.DB.CreateInBatches(&municipalities, batchSize).Error; err != nil {
+	if err := db.CreateInBatches(&municipalities, batchSize).Error; err != nil {
 		log.Fatal("Error inserting municipalities:", err)
 	}
 
 	fmt.Println("✅ Municipalities imported successfully")
 }
 
-func importBarangays() {
+func importBarangays(db *gorm.DB) {
 	records, err := loadCSV("csv-file/databarangay.csv")
 	if err != nil {
 		log.Fatal("Error reading barangay CSV:", err)
@@ -121,7 +123,7 @@ func importBarangays() {
 		})
 	}
 
-	if err := config.DB.CreateInBatches(&barangays, batchSize).Error; err != nil {
+	if err := db.CreateInBatches(&barangays, batchSize).Error; err != nil {
 		log.Fatal("Error inserting barangays:", err)
 	}
 
@@ -129,8 +131,20 @@ func importBarangays() {
 }
 
 func InsertCSV() {
-	importRegions()
-	importProvinces()
-	importMunicipalities()
-	importBarangays()
+	insertAll(config.DB)
+}
+
+// InsertCSVForTenant imports the CSV files into the schema of tenantID.
+func InsertCSVForTenant(tenantID string) {
+	if err := tenant.ValidateID(tenantID); err != nil {
+		log.Fatal("Error importing CSV:", err)
+	}
+	insertAll(tenant.Scoped(config.DB, tenantID))
+}
+
+func insertAll(db *gorm.DB) {
+	importRegions(db)
+	importProvinces(db)
+	importMunicipalities(db)
+	importBarangays(db)
 }
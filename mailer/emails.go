@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"sort"
+	"time"
+)
+
+// VerifyEmail asks a new user to confirm their email address.
+type VerifyEmail struct {
+	Name      string
+	Link      string
+	ExpiresIn time.Duration
+}
+
+func (VerifyEmail) TemplateName() string { return "verify_email" }
+
+// PasswordReset sends the link of a forgot password request.
+type PasswordReset struct {
+	Name      string
+	Link      string
+	ExpiresIn time.Duration
+}
+
+func (PasswordReset) TemplateName() string { return "password_reset" }
+
+// Action is a generic email with a message and a single button.
+type Action struct {
+	Subject    string
+	Message    string
+	ActionText string
+	Link       string
+}
+
+func (Action) TemplateName() string { return "action" }
+
+// samples holds the preview data of each template, see RegisterSample.
+var samples = map[string]Email{
+	"verify_email":   VerifyEmail{Name: "Juan", Link: "https://example.com/verify?token=sample", ExpiresIn: 24 * time.Hour},
+	"password_reset": PasswordReset{Name: "Juan", Link: "https://example.com/reset?token=sample", ExpiresIn: time.Hour},
+	"action":         Action{Subject: "Action required", Message: "Click the button below:", ActionText: "Open Link", Link: "https://example.com"},
+}
+
+// RegisterSample sets the data a template is previewed with, so project templates can be previewed too.
+func RegisterSample(email Email) {
+	samples[email.TemplateName()] = email
+}
+
+// Sample returns the preview data of a template.
+func Sample(name string) (Email, bool) {
+	email, ok := samples[name]
+	return email, ok
+}
+
+// Samples returns the names of the templates with preview data.
+func Samples() []string {
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,23 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// LogMailer logs emails instead of sending them, for development.
+type LogMailer struct {
+	From string
+}
+
+func (l *LogMailer) Send(ctx context.Context, msg *Message) error {
+	msg, err := msg.withDefaults(l.From)
+	if err != nil {
+		return err
+	}
+	log.Printf("📧 Email from %s to %s: %s (%d attachments)", msg.From, strings.Join(msg.Recipients(), ", "), msg.Subject, len(msg.Attachments))
+	return nil
+}
+
+func (l *LogMailer) Close() error { return nil }
@@ -0,0 +1,214 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// ErrNoRecipients is returned for a message without To, Cc or Bcc addresses.
+var ErrNoRecipients = errors.New("email has no recipients")
+
+// ErrInvalidAddress is returned for a message with a malformed sender, recipient or reply-to address.
+var ErrInvalidAddress = errors.New("invalid email address")
+
+// Attachment is a file sent with a message. Inline attachments are embedded images the HTML body
+// references as "cid:<ContentID>".
+type Attachment struct {
+	Filename    string
+	ContentType string // Detected from Filename when empty
+	Data        []byte
+	Inline      bool
+	ContentID   string // Defaults to Filename
+}
+
+// Message is an email. Addresses are RFC 5322 addresses, e.g. "Support <support@example.com>".
+type Message struct {
+	From        string // Defaults to the From of the Mailer
+	To          []string
+	Cc          []string
+	Bcc         []string
+	ReplyTo     string
+	Subject     string
+	Text        string
+	HTML        string
+	Headers     map[string]string
+	Attachments []Attachment
+}
+
+// Mailer sends emails. SMTP delivers them, LogMailer logs them and Memory records them for tests.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+var (
+	Default  Mailer
+	initOnce sync.Once
+	initErr  error
+)
+
+// Init selects Default from MAIL_DRIVER, unless it is already set: "smtp" (the default) is configured
+// by SMTPConfigFromEnv, "log" only logs emails and "memory" records them.
+func Init() Mailer {
+	m, err := load()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize mailer: %v", err)
+	}
+	return m
+}
+
+func load() (Mailer, error) {
+	initOnce.Do(func() {
+		if Default != nil {
+			return
+		}
+		if Default, initErr = New(os.Getenv("MAIL_DRIVER")); initErr == nil {
+			log.Println("✅ Mailer initialized")
+		}
+	})
+	return Default, initErr
+}
+
+// New returns the Mailer of driver configured from the environment.
+func New(driver string) (Mailer, error) {
+	switch driver {
+	case "", "smtp":
+		cfg := SMTPConfigFromEnv()
+		if cfg.Host == "" {
+			return nil, errors.New("MAIL_HOST is required for the smtp driver")
+		}
+		return NewSMTP(cfg), nil
+	case "log":
+		return &LogMailer{From: os.Getenv("MAIL_FROM_ADDRESS")}, nil
+	case "memory":
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+// Send sends msg with Default, initializing it if needed. Unlike Init, a missing configuration is
+// returned as an error.
+func Send(ctx context.Context, msg *Message) error {
+	m, err := load()
+	if err != nil {
+		return err
+	}
+	return m.Send(ctx, msg)
+}
+
+// Recipients returns the addresses of every To, Cc and Bcc recipient.
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, addr := range list {
+			if parsed, err := parseAddress(addr); err == nil {
+				recipients = append(recipients, parsed)
+			}
+		}
+	}
+	return recipients
+}
+
+// parseAddress returns the email address of an RFC 5322 address.
+func parseAddress(addr string) (string, error) {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Address, nil
+}
+
+// withDefaults returns a copy of m sent from from unless it has a sender, after validating its addresses.
+func (m *Message) withDefaults(from string) (*Message, error) {
+	msg := *m
+	if msg.From == "" {
+		msg.From = from
+	}
+	if _, err := mail.ParseAddress(msg.From); err != nil {
+		return nil, fmt.Errorf("%w: sender %q: %w", ErrInvalidAddress, msg.From, err)
+	}
+	if len(msg.To)+len(msg.Cc)+len(msg.Bcc) == 0 {
+		return nil, ErrNoRecipients
+	}
+	for _, list := range [][]string{msg.To, msg.Cc, msg.Bcc} {
+		for _, addr := range list {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return nil, fmt.Errorf("%w: recipient %q: %w", ErrInvalidAddress, addr, err)
+			}
+		}
+	}
+	if msg.ReplyTo != "" {
+		if _, err := mail.ParseAddress(msg.ReplyTo); err != nil {
+			return nil, fmt.Errorf("%w: reply-to %q: %w", ErrInvalidAddress, msg.ReplyTo, err)
+		}
+	}
+	return &msg, nil
+}
+
+// Bytes encodes m as a MIME message. Bcc recipients are left out of the headers.
+func (m *Message) Bytes() ([]byte, error) {
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", m.From)
+	if len(m.To) > 0 {
+		gm.SetHeader("To", m.To...)
+	}
+	if len(m.Cc) > 0 {
+		gm.SetHeader("Cc", m.Cc...)
+	}
+	if m.ReplyTo != "" {
+		gm.SetHeader("Reply-To", m.ReplyTo)
+	}
+	gm.SetHeader("Subject", m.Subject)
+	gm.SetDateHeader("Date", time.Now())
+	for key, value := range m.Headers {
+		gm.SetHeader(key, value)
+	}
+
+	switch {
+	case m.Text != "" && m.HTML != "":
+		gm.SetBody("text/plain", m.Text)
+		gm.AddAlternative("text/html", m.HTML)
+	case m.HTML != "":
+		gm.SetBody("text/html", m.HTML)
+	default:
+		gm.SetBody("text/plain", m.Text)
+	}
+
+	for _, a := range m.Attachments {
+		data := a.Data
+		settings := []gomail.FileSetting{gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})}
+		if a.ContentType != "" {
+			settings = append(settings, gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}))
+		}
+		if a.Inline {
+			id := a.ContentID
+			if id == "" {
+				id = a.Filename
+			}
+			settings = append(settings, gomail.SetHeader(map[string][]string{"Content-ID": {"<" + id + ">"}}))
+			gm.Embed(a.Filename, settings...)
+		} else {
+			gm.Attach(a.Filename, settings...)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := gm.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("failed to encode email: %w", err)
+	}
+	return buf.Bytes(), nil
+}
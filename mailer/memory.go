@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory records emails instead of sending them, for tests. Err, when set, fails every send.
+type Memory struct {
+	mu   sync.Mutex
+	Sent []*Message
+	From string
+	Err  error
+}
+
+// NewMemory returns an empty Memory mailer sending from "test@localhost".
+func NewMemory() *Memory {
+	return &Memory{From: "test@localhost"}
+}
+
+func (m *Memory) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	msg, err := msg.withDefaults(m.From)
+	if err != nil {
+		return err
+	}
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+// Messages returns a copy of the recorded messages.
+func (m *Memory) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.Sent...)
+}
+
+// Last returns the last recorded message, or nil.
+func (m *Memory) Last() *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Sent) == 0 {
+		return nil
+	}
+	return m.Sent[len(m.Sent)-1]
+}
+
+// Reset forgets the recorded messages.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sent = nil
+}
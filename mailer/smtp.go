@@ -0,0 +1,377 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS modes of an SMTP connection
+const (
+	TLSAuto     = "auto"     // Upgrade with STARTTLS when the server supports it, stay plain otherwise
+	TLSStartTLS = "starttls" // Upgrade a plain connection; fail if the server does not support it
+	TLSImplicit = "ssl"      // Connect over TLS, usually on port 465
+	TLSNone     = "none"     // Never encrypt, for local relays only
+)
+
+// ErrMailerClosed is returned when sending with a closed SMTP mailer.
+var ErrMailerClosed = errors.New("mailer is closed")
+
+// RetryPolicy retries sends failing with temporary errors: network errors and 4xx replies.
+// The delay doubles after every attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy tries 3 times, 1 and then 2 seconds apart.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: time.Second, MaxBackoff: 30 * time.Second}
+
+// SMTPConfig configures an SMTP mailer.
+type SMTPConfig struct {
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	From        string
+	TLS         string // TLSAuto (the default), TLSStartTLS, TLSImplicit or TLSNone
+	TLSConfig   *tls.Config
+	LocalName   string        // Sent with EHLO, defaults to "localhost"
+	PoolSize    int           // Maximum number of open connections
+	IdleTimeout time.Duration // Idle connections older than this are closed instead of reused
+	Timeout     time.Duration // Dial and send timeout when the context has no deadline
+	Retry       RetryPolicy
+}
+
+// SMTPConfigFromEnv reads MAIL_HOST, MAIL_PORT (587), MAIL_USERNAME, MAIL_PASSWORD, MAIL_FROM_ADDRESS
+// and MAIL_ENCRYPTION ("auto", "starttls", "ssl" or "none"; "ssl" by default on port 465, "auto" otherwise).
+func SMTPConfigFromEnv() SMTPConfig {
+	port := 587
+	if parsed, err := strconv.Atoi(os.Getenv("MAIL_PORT")); err == nil {
+		port = parsed
+	}
+
+	mode := strings.ToLower(os.Getenv("MAIL_ENCRYPTION"))
+	if mode == "" {
+		mode = TLSAuto
+		if port == 465 {
+			mode = TLSImplicit
+		}
+	}
+
+	return SMTPConfig{
+		Host:     os.Getenv("MAIL_HOST"),
+		Port:     port,
+		Username: os.Getenv("MAIL_USERNAME"),
+		Password: os.Getenv("MAIL_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM_ADDRESS"),
+		TLS:      mode,
+	}
+}
+
+// SMTP sends emails through an SMTP server, keeping up to PoolSize authenticated connections open
+// between sends.
+type SMTP struct {
+	cfg SMTPConfig
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	slots  chan struct{}
+	closed bool
+}
+
+type smtpConn struct {
+	client   *smtp.Client
+	conn     net.Conn
+	lastUsed time.Time
+}
+
+// NewSMTP returns an SMTP mailer. Connections are opened on the first send.
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	if cfg.TLS == "" {
+		cfg.TLS = TLSAuto
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 4
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Retry.Attempts <= 0 {
+		cfg.Retry = DefaultRetryPolicy
+	}
+	return &SMTP{cfg: cfg, slots: make(chan struct{}, cfg.PoolSize)}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	msg, err := msg.withDefaults(s.cfg.From)
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, _ := parseAddress(msg.From)
+	recipients := msg.Recipients()
+
+	backoff := s.cfg.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err = s.send(ctx, from, recipients, data)
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.cfg.Retry.Attempts || !temporary(err) {
+			return fmt.Errorf("failed to send email to %s: %w", strings.Join(msg.To, ", "), err)
+		}
+
+		log.Printf("⚠️ Failed to send email (attempt %d/%d), retrying in %s: %v", attempt, s.cfg.Retry.Attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.cfg.Retry.MaxBackoff)
+	}
+}
+
+// send delivers one message over a pooled connection. Connections that fail are discarded.
+func (s *SMTP) send(ctx context.Context, from string, recipients []string, data []byte) error {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.slots }()
+
+	c, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.cfg.Timeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	if err := deliver(c.client, from, recipients, data); err != nil {
+		// A rejected recipient leaves the connection usable once the transaction is reset
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && c.client.Reset() == nil {
+			s.put(c)
+		} else {
+			c.client.Close()
+		}
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+func deliver(client *smtp.Client, from string, recipients []string, data []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// get returns an idle connection that still answers NOOP, or dials a new one.
+func (s *SMTP) get(ctx context.Context) (*smtpConn, error) {
+	for {
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return nil, ErrMailerClosed
+		}
+		if len(s.idle) == 0 {
+			s.mu.Unlock()
+			return s.dial(ctx)
+		}
+		c := s.idle[len(s.idle)-1]
+		s.idle = s.idle[:len(s.idle)-1]
+		s.mu.Unlock()
+
+		if time.Since(c.lastUsed) > s.cfg.IdleTimeout {
+			c.quit()
+			continue
+		}
+		c.conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+		if err := c.client.Noop(); err != nil {
+			c.client.Close()
+			continue
+		}
+		return c, nil
+	}
+}
+
+func (s *SMTP) put(c *smtpConn) {
+	c.lastUsed = time.Now()
+	c.conn.SetDeadline(time.Time{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		c.quit()
+		return
+	}
+	s.idle = append(s.idle, c)
+}
+
+func (s *SMTP) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+
+	tlsConfig := s.cfg.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: s.cfg.Host}
+	}
+	if s.cfg.TLS == TLSImplicit {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := s.handshake(client, tlsConfig); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &smtpConn{client: client, conn: conn}, nil
+}
+
+func (s *SMTP) handshake(client *smtp.Client, tlsConfig *tls.Config) error {
+	if s.cfg.LocalName != "" {
+		if err := client.Hello(s.cfg.LocalName); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.TLS == TLSStartTLS || s.cfg.TLS == TLSAuto {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok && s.cfg.TLS == TLSStartTLS {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.cfg.Username == "" {
+		return nil
+	}
+	ok, mechanisms := client.Extension("AUTH")
+	if !ok {
+		return errors.New("smtp server does not support authentication")
+	}
+	if strings.Contains(mechanisms, "PLAIN") || !strings.Contains(mechanisms, "LOGIN") {
+		return client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host))
+	}
+	return client.Auth(&loginAuth{username: s.cfg.Username, password: s.cfg.Password})
+}
+
+// Close closes the idle connections. Sends after Close fail with ErrMailerClosed.
+func (s *SMTP) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, c := range s.idle {
+		c.quit()
+	}
+	s.idle = nil
+	return nil
+}
+
+func (c *smtpConn) quit() {
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := c.client.Quit(); err != nil {
+		c.client.Close()
+	}
+}
+
+// temporary reports whether sending again may succeed: network errors and 4xx replies.
+func temporary(err error) bool {
+	if errors.Is(err, ErrMailerClosed) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &netErr) || errors.As(err, &opErr) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Permanent reports whether sending again cannot succeed: 5xx replies and invalid messages.
+func Permanent(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 500
+	}
+	return errors.Is(err, ErrNoRecipients) || errors.Is(err, ErrInvalidAddress)
+}
+
+// loginAuth implements the LOGIN mechanism required by servers without PLAIN, e.g. Office 365.
+type loginAuth struct {
+	username, password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
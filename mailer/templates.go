@@ -0,0 +1,309 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// ErrTemplateNotFound is returned when no layer has the files of a template.
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// Email is the typed data of a transactional email. Its template is made of <name>.txt, which
+// defines the "subject" and the plain text "content", and <name>.html, which defines the HTML
+// "content". Both are rendered inside layout.txt and layout.html.
+type Email interface {
+	TemplateName() string
+}
+
+// Brand holds the branding variables available to every template as .Brand.
+type Brand struct {
+	Name           string
+	URL            string
+	LogoURL        string
+	FooterImageURL string
+	PrimaryColor   string
+	SupportEmail   string
+	Signature      string
+}
+
+// BrandFromEnv reads MAIL_BRAND_NAME, MAIL_BRAND_URL, MAIL_BRAND_LOGO_URL, MAIL_FOOTER_IMAGE_URL,
+// MAIL_BRAND_COLOR, MAIL_SUPPORT_ADDRESS and MAIL_SIGNATURE.
+func BrandFromEnv() Brand {
+	brand := Brand{
+		Name:           os.Getenv("MAIL_BRAND_NAME"),
+		URL:            os.Getenv("MAIL_BRAND_URL"),
+		LogoURL:        os.Getenv("MAIL_BRAND_LOGO_URL"),
+		FooterImageURL: os.Getenv("MAIL_FOOTER_IMAGE_URL"),
+		PrimaryColor:   os.Getenv("MAIL_BRAND_COLOR"),
+		SupportEmail:   os.Getenv("MAIL_SUPPORT_ADDRESS"),
+		Signature:      os.Getenv("MAIL_SIGNATURE"),
+	}
+	if brand.PrimaryColor == "" {
+		brand.PrimaryColor = "#4CAF50"
+	}
+	if brand.Signature == "" {
+		brand.Signature = "Thank you, Support Team"
+	}
+	return brand
+}
+
+// TemplateData is the data templates are executed with.
+type TemplateData struct {
+	Brand   Brand
+	Locale  string
+	Subject string // Set when rendering the layouts
+	Year    int
+	Data    Email
+}
+
+// Templates renders emails from layers of template files. Every file is looked up in the last
+// layer first, so a project overrides single templates by adding a layer with only those files.
+// Localized files live in a directory per locale, e.g. "fil/verify_email.txt", and fall back to
+// the base language, DefaultLocale and finally the top level files.
+type Templates struct {
+	Brand         Brand
+	DefaultLocale string
+
+	mu     sync.RWMutex
+	layers []fs.FS
+	cache  map[string]*compiled
+}
+
+type compiled struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var (
+	DefaultTemplates *Templates
+	templatesOnce    sync.Once
+)
+
+// LoadTemplates returns DefaultTemplates: the built-in templates with the files of MAIL_TEMPLATE_DIR
+// on top, branded by BrandFromEnv.
+func LoadTemplates() *Templates {
+	templatesOnce.Do(func() {
+		if DefaultTemplates != nil {
+			return
+		}
+		DefaultTemplates = NewTemplates(BrandFromEnv())
+		if dir := os.Getenv("MAIL_TEMPLATE_DIR"); dir != "" {
+			DefaultTemplates.Override(os.DirFS(dir))
+			log.Printf("✅ Email templates loaded from %s", dir)
+		}
+	})
+	return DefaultTemplates
+}
+
+// NewTemplates returns the built-in templates with layers on top, e.g. an embed.FS of the project.
+func NewTemplates(brand Brand, layers ...fs.FS) *Templates {
+	builtin, _ := fs.Sub(embedded, "templates")
+	return &Templates{
+		Brand:         brand,
+		DefaultLocale: "en",
+		layers:        append([]fs.FS{builtin}, layers...),
+		cache:         make(map[string]*compiled),
+	}
+}
+
+// Override adds a layer on top of the existing ones.
+func (t *Templates) Override(fsys fs.FS) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.layers = append(t.layers, fsys)
+	t.cache = make(map[string]*compiled)
+}
+
+// Render renders email in locale into a Message with its Subject, Text and HTML set.
+func (t *Templates) Render(locale string, email Email) (*Message, error) {
+	tmpl, err := t.compile(locale, email.TemplateName())
+	if err != nil {
+		return nil, err
+	}
+
+	data := TemplateData{Brand: t.Brand, Locale: locale, Year: time.Now().Year(), Data: email}
+	if data.Locale == "" {
+		data.Locale = t.DefaultLocale
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", email.TemplateName(), err)
+	}
+	data.Subject = strings.TrimSpace(subject.String())
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s.txt: %w", email.TemplateName(), err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s.html: %w", email.TemplateName(), err)
+	}
+
+	return &Message{Subject: data.Subject, Text: strings.TrimSpace(text.String()), HTML: html.String()}, nil
+}
+
+// Send renders email in locale and sends it to recipients with m.
+func (t *Templates) Send(ctx context.Context, m Mailer, locale string, to []string, email Email) error {
+	msg, err := t.Render(locale, email)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return m.Send(ctx, msg)
+}
+
+// SendTemplate renders email with DefaultTemplates and sends it with Default.
+func SendTemplate(ctx context.Context, locale string, to []string, email Email) error {
+	msg, err := LoadTemplates().Render(locale, email)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return Send(ctx, msg)
+}
+
+// compile returns the templates of name in locale. They are cached by the locale directories found,
+// so the cache only grows with the locales that exist.
+func (t *Templates) compile(locale, name string) (*compiled, error) {
+	t.mu.RLock()
+	locales := t.locales(locale)
+	key := strings.Join(locales, ",") + name
+	tmpl, ok := t.cache[key]
+	t.mu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// A layer may have been added meanwhile
+	locales = t.locales(locale)
+	key = strings.Join(locales, ",") + name
+	read := func(file string) (string, error) {
+		for _, dir := range locales {
+			for i := len(t.layers) - 1; i >= 0; i-- {
+				if data, err := fs.ReadFile(t.layers[i], dir+file); err == nil {
+					return string(data), nil
+				}
+			}
+		}
+		return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, file)
+	}
+
+	textLayout, err := read("layout.txt")
+	if err != nil {
+		return nil, err
+	}
+	htmlLayout, err := read("layout.html")
+	if err != nil {
+		return nil, err
+	}
+	textContent, err := read(name + ".txt")
+	if err != nil {
+		return nil, err
+	}
+	htmlContent, err := read(name + ".html")
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl = &compiled{}
+	if tmpl.text, err = texttemplate.New("layout.txt").Funcs(funcs).Parse(textLayout); err == nil {
+		_, err = tmpl.text.New(name + ".txt").Parse(textContent)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.txt: %w", name, err)
+	}
+	if tmpl.html, err = htmltemplate.New("layout.html").Funcs(funcs).Parse(htmlLayout); err == nil {
+		_, err = tmpl.html.New(name + ".html").Parse(htmlContent)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s.html: %w", name, err)
+	}
+
+	t.cache[key] = tmpl
+	return tmpl, nil
+}
+
+// locales returns the existing directories searched for locale, most specific first. Both fil_PH
+// and fil-PH fall back to fil.
+func (t *Templates) locales(locale string) []string {
+	var dirs []string
+	add := func(l string) {
+		if l == "" || !t.hasLocale(l) {
+			return
+		}
+		for _, dir := range dirs {
+			if dir == l+"/" {
+				return
+			}
+		}
+		dirs = append(dirs, l+"/")
+	}
+	locale = normalizeLocale(locale)
+	add(locale)
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		add(base)
+	}
+	add(normalizeLocale(t.DefaultLocale))
+	return append(dirs, "")
+}
+
+// hasLocale reports whether a layer has a directory for locale.
+func (t *Templates) hasLocale(locale string) bool {
+	for _, layer := range t.layers {
+		if info, err := fs.Stat(layer, locale); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// button is the data of the "button" template of layout.html.
+type button struct {
+	Link  string
+	Text  string
+	Color string
+}
+
+var funcs = map[string]interface{}{
+	"button": func(link, text, color string) button {
+		return button{Link: link, Text: text, Color: color}
+	},
+	"duration": formatDuration,
+}
+
+// formatDuration formats d in words, e.g. "24 hours" or "30 minutes".
+func formatDuration(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(max(int(d/time.Minute), 1), "minute")
+	}
+}
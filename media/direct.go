@@ -0,0 +1,115 @@
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/storage"
+	"gorm.io/datatypes"
+)
+
+// ErrUploadNotFound is returned when completing an upload that does not exist or belongs to another user.
+var ErrUploadNotFound = errors.New("upload not found")
+
+// pendingPrefix holds the files clients upload directly. They are deleted once processed; a bucket
+// lifecycle rule should delete the ones never completed, e.g. after a day.
+const pendingPrefix = "uploads/users/%d/"
+
+// RequestUpload returns a signed URL userID uploads an image of contentType to directly, limited to
+// Config.MaxBytes and valid for Config.UploadExpiry. The upload is processed by CompleteUserImage.
+func (s *Service) RequestUpload(ctx context.Context, userID int, contentType string) (*storage.UploadURL, error) {
+	if !contains(s.Config.AllowedTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return s.Storage.SignedUploadURL(ctx, fmt.Sprintf(pendingPrefix, userID)+hex.EncodeToString(id), storage.SignOptions{
+		ContentType: contentType,
+		MaxSize:     s.Config.MaxBytes,
+		Expires:     s.Config.UploadExpiry,
+	})
+}
+
+// CompleteUserImage processes the file userID uploaded to key through RequestUpload and saves it as
+// their imageType image. The uploaded file is deleted once saved or when it is not a valid image, and
+// kept on other failures so the client can complete it again.
+func (s *Service) CompleteUserImage(ctx context.Context, userID int, imageType, key string) (*sharedModels.UserImage, error) {
+	if !imageTypePattern.MatchString(imageType) {
+		return nil, ErrInvalidImageType
+	}
+	if !strings.HasPrefix(key, fmt.Sprintf(pendingPrefix, userID)) {
+		return nil, ErrUploadNotFound
+	}
+
+	r, obj, err := s.Storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var image *sharedModels.UserImage
+	if obj.Size > s.Config.MaxBytes {
+		err = ErrTooLarge
+	} else {
+		image, err = s.SaveUserImage(ctx, userID, imageType, r)
+	}
+	r.Close()
+
+	if err == nil || errors.Is(err, ErrTooLarge) || errors.Is(err, ErrUnsupportedType) || errors.Is(err, ErrDimensions) {
+		s.Storage.Delete(context.WithoutCancel(ctx), key)
+	}
+	return image, err
+}
+
+// SignUserImage replaces the URLs of image with signed URLs valid for Config.URLExpiry when Config.Private
+// is set, for buckets that are not public. The stored image is left unchanged.
+func (s *Service) SignUserImage(ctx context.Context, image *sharedModels.UserImage) error {
+	if !s.Config.Private || image.ImageKey == "" {
+		return nil
+	}
+
+	var err error
+	image.ImageURL, image.Variants, err = s.sign(ctx, image.ImageKey, image.ImageURL, image.Variants)
+	return err
+}
+
+// SignAdvertisement is SignUserImage for the image of an advertisement.
+func (s *Service) SignAdvertisement(ctx context.Context, ad *sharedModels.Advertisement) error {
+	if !s.Config.Private || ad.ImageKey == "" {
+		return nil
+	}
+
+	var err error
+	ad.URLImage, ad.ImageVariants, err = s.sign(ctx, ad.ImageKey, ad.URLImage, ad.ImageVariants)
+	return err
+}
+
+// sign returns signed URLs of the original and the variants stored under key. Every variant has the
+// extension of the original, see Store.
+func (s *Service) sign(ctx context.Context, key, original string, variants datatypes.JSONMap) (string, datatypes.JSONMap, error) {
+	ext := path.Ext(original)
+	opts := storage.SignOptions{Expires: s.Config.URLExpiry}
+
+	url, err := s.Storage.SignedURL(ctx, key+"/"+OriginalVariant+ext, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	signed := datatypes.JSONMap{}
+	for name := range variants {
+		if signed[name], err = s.Storage.SignedURL(ctx, key+"/"+name+ext, opts); err != nil {
+			return "", nil, err
+		}
+	}
+	return url, signed, nil
+}
@@ -0,0 +1,176 @@
+package media
+
+import (
+	"errors"
+	"mime/multipart"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// UploadURLRequest is the body of UploadURLHandler.
+type UploadURLRequest struct {
+	ContentType string `json:"content_type"`
+}
+
+// CompleteUploadRequest is the body of CompleteUploadHandler.
+type CompleteUploadRequest struct {
+	Key       string `json:"key"`
+	ImageType string `json:"image_type"`
+}
+
+// RegisterRoutes mounts the image endpoints of the authenticated user on router, behind JWTAuthMiddleware:
+// GET / lists their images, POST / uploads one through the API, POST /upload-url returns a signed URL
+// to upload one directly to storage and POST /complete saves it.
+func (s *Service) RegisterRoutes(router fiber.Router) {
+	router.Get("/", s.UserImagesHandler)
+	router.Post("/", s.UserImageHandler)
+	router.Post("/upload-url", s.UploadURLHandler)
+	router.Post("/complete", s.CompleteUploadHandler)
+}
+
+// UserImagesHandler returns the images of the authenticated user.
+func (s *Service) UserImagesHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var images []sharedModels.UserImage
+	if err := s.DB.WithContext(c.Context()).Where("user_id = ?", userID).Order("id").Find(&images).Error; err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	for i := range images {
+		if err := s.SignUserImage(c.Context(), &images[i]); err != nil {
+			return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+		}
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, images)
+}
+
+// UploadURLHandler returns a signed URL the authenticated user uploads an image to directly. The
+// client then PUTs the file to the URL with the returned headers and calls CompleteUploadHandler.
+func (s *Service) UploadURLHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var req UploadURLRequest
+	if err := c.Bind().Body(&req); err != nil || req.ContentType == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Content type is required")
+	}
+
+	upload, err := s.RequestUpload(c.Context(), userID, req.ContentType)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, upload)
+}
+
+// CompleteUploadHandler saves the file uploaded through UploadURLHandler as the "image_type" image of
+// the authenticated user.
+func (s *Service) CompleteUploadHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var req CompleteUploadRequest
+	if err := c.Bind().Body(&req); err != nil || req.Key == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Key is required")
+	}
+
+	image, err := s.CompleteUserImage(c.Context(), userID, req.ImageType, req.Key)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	if err := s.SignUserImage(c.Context(), image); err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, image)
+}
+
+// UserImageHandler stores the "file" of a multipart upload as the "image_type" image of the authenticated user.
+// The app's BodyLimit must allow Config.MaxBytes; prefer UploadURLHandler for large files.
+func (s *Service) UserImageHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	file, err := s.openUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	defer file.Close()
+
+	image, err := s.SaveUserImage(c.Context(), userID, c.FormValue("image_type"), file)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	if err := s.SignUserImage(c.Context(), image); err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, image)
+}
+
+// AdvertisementImageHandler stores the "file" of a multipart upload as the image of the advertisement in :id.
+func (s *Service) AdvertisementImageHandler(c fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid advertisement ID")
+	}
+
+	file, err := s.openUpload(c)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	defer file.Close()
+
+	ad, err := s.SaveAdvertisementImage(c.Context(), id, file)
+	if err != nil {
+		return uploadError(c, err)
+	}
+	if err := s.SignAdvertisement(c.Context(), ad); err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, ad)
+}
+
+// errMissingFile is returned when the multipart form has no "file".
+var errMissingFile = errors.New("file is required")
+
+func (s *Service) openUpload(c fiber.Ctx) (multipart.File, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, errMissingFile
+	}
+	if header.Size > s.Config.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	return header.Open()
+}
+
+func uploadError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errMissingFile), errors.Is(err, ErrUnsupportedType), errors.Is(err, ErrTooLarge),
+		errors.Is(err, ErrDimensions), errors.Is(err, ErrInvalidImageType):
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG, err)
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, ErrUploadNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	default:
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+}
+
+// currentUserID returns the user set in the request context by JWTAuthMiddleware.
+func currentUserID(c fiber.Ctx) (int, bool) {
+	userID, err := strconv.Atoi(utils.ActorFromContext(c.Context()))
+	return userID, err == nil && userID > 0
+}
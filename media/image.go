@@ -0,0 +1,371 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Validation errors
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooLarge        = errors.New("image file is too large")
+	ErrDimensions      = errors.New("image dimensions out of range")
+)
+
+// Variant is a resized copy of an upload. The image is scaled down to fit within Width x Height,
+// or cropped to exactly that size around its center when Crop is set. Images are never scaled up.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+// ImageConfig holds the limits and variants of uploaded images.
+type ImageConfig struct {
+	MaxBytes     int64
+	MinWidth     int
+	MinHeight    int
+	MaxWidth     int // Larger images are rejected before being decoded
+	MaxHeight    int
+	MaxPixels    int      // Width x height, bounding the memory used to decode; 4 bytes each
+	AllowedTypes []string // Detected from the magic bytes
+	Quality      int      // JPEG quality of the re-encoded images
+	Variants     []Variant
+
+	UploadExpiry time.Duration // Validity of direct upload URLs, see Service.RequestUpload
+	Private      bool          // Respond with signed URLs instead of public ones
+	URLExpiry    time.Duration // Validity of the signed URLs of a Private config
+}
+
+// DefaultImageConfig accepts JPEG, PNG and GIF images up to 10 MB, 8000x8000 and 40 megapixels, and
+// generates a large, a medium and a square thumbnail variant.
+var DefaultImageConfig = ImageConfig{
+	MaxBytes:     10 << 20,
+	MinWidth:     16,
+	MinHeight:    16,
+	MaxWidth:     8000,
+	MaxHeight:    8000,
+	MaxPixels:    40_000_000,
+	AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+	Quality:      85,
+	Variants: []Variant{
+		{Name: "large", Width: 1600, Height: 1600},
+		{Name: "medium", Width: 800, Height: 800},
+		{Name: "thumb", Width: 200, Height: 200, Crop: true},
+	},
+	UploadExpiry: 15 * time.Minute,
+	URLExpiry:    time.Hour,
+}
+
+// ProcessConcurrency is the number of images Process decodes at once, the others wait. Each holds up
+// to a few copies of MaxPixels x 4 bytes. Set it before the first call to Process.
+var ProcessConcurrency = 2
+
+var (
+	processSlots     chan struct{}
+	processSlotsOnce sync.Once
+)
+
+// acquireSlot waits for one of the ProcessConcurrency slots and returns its release.
+func acquireSlot() func() {
+	processSlotsOnce.Do(func() {
+		processSlots = make(chan struct{}, max(ProcessConcurrency, 1))
+	})
+	processSlots <- struct{}{}
+	return func() { <-processSlots }
+}
+
+// OriginalVariant is the name of the full size, metadata-free copy of an upload.
+const OriginalVariant = "original"
+
+// EncodedImage is one variant ready to be stored.
+type EncodedImage struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// ProcessedImage is a validated upload with its variants. Hash identifies the uploaded bytes.
+type ProcessedImage struct {
+	Hash     string
+	Variants []EncodedImage // The original first
+}
+
+// Process validates the image in r against cfg and encodes the original and every variant. Images are
+// decoded and encoded again, which drops EXIF and other metadata; the EXIF orientation is applied first.
+func Process(r io.Reader, cfg ImageConfig) (*ProcessedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(r, cfg.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > cfg.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !contains(cfg.AllowedTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if header.Width < cfg.MinWidth || header.Height < cfg.MinHeight ||
+		(cfg.MaxWidth > 0 && header.Width > cfg.MaxWidth) || (cfg.MaxHeight > 0 && header.Height > cfg.MaxHeight) ||
+		(cfg.MaxPixels > 0 && header.Width*header.Height > cfg.MaxPixels) {
+		return nil, fmt.Errorf("%w: %dx%d", ErrDimensions, header.Width, header.Height)
+	}
+
+	sum := sha256.Sum256(data)
+	processed := &ProcessedImage{Hash: hex.EncodeToString(sum[:])}
+	orientation := 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	release := acquireSlot()
+	defer release()
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	src := toRGBA(decoded)
+	src = orient(src, orientation)
+
+	// JPEG stays JPEG; PNG and GIF keep their transparency as PNG
+	encode := func(name string, img *image.RGBA) error {
+		var buf bytes.Buffer
+		var err error
+		out := EncodedImage{Name: name, Width: img.Rect.Dx(), Height: img.Rect.Dy()}
+		if contentType == "image/jpeg" {
+			out.ContentType, out.Extension = "image/jpeg", ".jpg"
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality(cfg.Quality)})
+		} else {
+			out.ContentType, out.Extension = "image/png", ".png"
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		out.Data = buf.Bytes()
+		processed.Variants = append(processed.Variants, out)
+		return nil
+	}
+
+	if err := encode(OriginalVariant, src); err != nil {
+		return nil, err
+	}
+	for _, variant := range cfg.Variants {
+		if err := encode(variant.Name, resize(src, variant)); err != nil {
+			return nil, err
+		}
+	}
+	return processed, nil
+}
+
+func quality(q int) int {
+	if q <= 0 || q > 100 {
+		return jpeg.DefaultQuality
+	}
+	return q
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize scales src down to fit variant, cropping it to the variant's aspect ratio first when Crop is set.
+func resize(src *image.RGBA, variant Variant) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	crop := src.Rect
+
+	if variant.Crop && variant.Width > 0 && variant.Height > 0 {
+		// Largest centered area with the variant's aspect ratio
+		cw, ch := sw, sw*variant.Height/variant.Width
+		if ch > sh {
+			cw, ch = sh*variant.Width/variant.Height, sh
+		}
+		x0, y0 := (sw-cw)/2, (sh-ch)/2
+		crop = image.Rect(x0, y0, x0+cw, y0+ch)
+		sw, sh = cw, ch
+	}
+
+	dw, dh := fit(sw, sh, variant.Width, variant.Height)
+	return scale(src.SubImage(crop).(*image.RGBA), dw, dh)
+}
+
+// fit returns the largest size within maxW x maxH with the aspect ratio of w x h, without scaling up.
+func fit(w, h, maxW, maxH int) (int, int) {
+	if maxW <= 0 || maxW > w {
+		maxW = w
+	}
+	if maxH <= 0 || maxH > h {
+		maxH = h
+	}
+	if w*maxH > h*maxW {
+		return maxW, max(1, h*maxW/w)
+	}
+	return max(1, w*maxH/h), maxH
+}
+
+// scale resizes src to dw x dh by averaging the source pixels covered by each destination pixel.
+func scale(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if sw == dw && sh == dh {
+		draw.Draw(dst, dst.Rect, src, src.Rect.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.PixOffset(src.Rect.Min.X+x0, src.Rect.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					p := src.Pix[row : row+4 : row+4]
+					r, g, b, a = r+uint64(p[0]), g+uint64(p[1]), b+uint64(p[2]), a+uint64(p[3])
+					n++
+					row += 4
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) to src.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // Flipped
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			s, d := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 1 when it has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			// Start of scan: no more metadata segments
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads the Orientation tag (0x0112) of the first IFD of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package media
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name             string
+		w, h, maxW, maxH int
+		wantW, wantH     int
+	}{
+		{"landscape", 400, 200, 100, 100, 100, 50},
+		{"portrait", 200, 400, 100, 100, 50, 100},
+		{"exact", 100, 100, 100, 100, 100, 100},
+		{"never scales up", 50, 20, 100, 100, 50, 20},
+		{"unbounded width", 400, 200, 0, 100, 200, 100},
+		{"unbounded height", 400, 200, 100, 0, 100, 50},
+		{"unbounded", 400, 200, 0, 0, 400, 200},
+		{"keeps a pixel", 1000, 1, 10, 10, 10, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := fit(tt.w, tt.h, tt.maxW, tt.maxH)
+			if w != tt.wantW || h != tt.wantH {
+				t.Errorf("fit(%d, %d, %d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves returns a w x h image, red on its left half and blue on its right half.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.SetRGBA(x, y, red)
+			} else {
+				img.SetRGBA(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name         string
+		variant      Variant
+		wantW, wantH int
+		left, right  color.RGBA // Colors of the first and last pixel of the middle row
+	}{
+		{"fits", Variant{Width: 100, Height: 100}, 100, 50, red, blue},
+		{"never scales up", Variant{Width: 400, Height: 400}, 200, 100, red, blue},
+		{"unbounded", Variant{}, 200, 100, red, blue},
+		{"crops square", Variant{Width: 50, Height: 50, Crop: true}, 50, 50, red, blue},
+		{"crops portrait", Variant{Width: 20, Height: 40, Crop: true}, 20, 40, red, blue},
+		{"crops within the center", Variant{Width: 40, Height: 100, Crop: true}, 40, 100, red, blue},
+		{"crops wider", Variant{Width: 100, Height: 20, Crop: true}, 100, 20, red, blue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resize(halves(200, 100), tt.variant)
+			if w, h := got.Rect.Dx(), got.Rect.Dy(); w != tt.wantW || h != tt.wantH {
+				t.Fatalf("resize() = %dx%d, want %dx%d", w, h, tt.wantW, tt.wantH)
+			}
+			if got.Rect.Min != (image.Point{}) {
+				t.Errorf("resize() starts at %v, want the origin", got.Rect.Min)
+			}
+			y := tt.wantH / 2
+			if c := got.RGBAAt(0, y); c != tt.left {
+				t.Errorf("left pixel = %v, want %v", c, tt.left)
+			}
+			if c := got.RGBAAt(tt.wantW-1, y); c != tt.right {
+				t.Errorf("right pixel = %v, want %v", c, tt.right)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// Pixels are told apart by their red value:
+	//	1 2 3
+	//	4 5 6
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.SetRGBA(i%3, i/3, color.RGBA{R: uint8(i + 1), A: 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8 // Rows of the oriented image
+	}{
+		{0, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{9, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if w, h := got.Rect.Dx(), got.Rect.Dy(); w != len(tt.want[0]) || h != len(tt.want) {
+			t.Errorf("orient(%d) = %dx%d, want %dx%d", tt.orientation, w, h, len(tt.want[0]), len(tt.want))
+			continue
+		}
+		for y, row := range tt.want {
+			for x, want := range row {
+				if c := got.RGBAAt(x, y); c.R != want {
+					t.Errorf("orient(%d) at %d,%d = %d, want %d", tt.orientation, x, y, c.R, want)
+				}
+			}
+		}
+	}
+}
+
+// exifJPEG returns the start of a JPEG with an APP1 Exif segment holding orientation in byte order.
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // First IFD
+	order.PutUint16(tiff[8:], 1) // Entries
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"little endian", exifJPEG(binary.LittleEndian, 6), 6},
+		{"big endian", exifJPEG(binary.BigEndian, 3), 3},
+		{"after another segment", append([]byte{0xFF, 0xD8}, append(app0, exifJPEG(binary.BigEndian, 8)[2:]...)...), 8},
+		{"no exif", append([]byte{0xFF, 0xD8}, append(app0, 0xFF, 0xDA, 0x00, 0x02)...), 1},
+		{"exif after the scan", append([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}, exifJPEG(binary.BigEndian, 6)[2:]...), 1},
+		{"truncated", exifJPEG(binary.LittleEndian, 6)[:20], 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
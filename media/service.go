@@ -0,0 +1,186 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/storage"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidImageType is returned for a UserImage type that cannot be used in a storage key.
+var ErrInvalidImageType = errors.New("invalid image type")
+
+var imageTypePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+// immutable is the Cache-Control of uploaded images: their keys change whenever their content does.
+const immutable = "public, max-age=31536000, immutable"
+
+// Service processes uploaded images and stores them with their variants.
+type Service struct {
+	DB      *gorm.DB
+	Storage storage.Storage
+	Config  ImageConfig
+}
+
+// NewService returns a Service storing images in store with DefaultImageConfig.
+func NewService(db *gorm.DB, store storage.Storage) *Service {
+	return &Service{DB: db, Storage: store, Config: DefaultImageConfig}
+}
+
+// Upload is the result of storing a processed image.
+type Upload struct {
+	Key      string            // Storage prefix shared by the variants
+	URL      string            // URL of the original
+	Variants datatypes.JSONMap // Variant name => URL
+
+	existed bool // The same content was already stored under Key, e.g. by the image it replaces
+}
+
+// Store processes the image in r and stores its variants under prefix/<hash>/<variant>.<ext>, so
+// uploading the same file twice gives the same keys.
+func (s *Service) Store(ctx context.Context, prefix string, r io.Reader) (*Upload, error) {
+	processed, err := Process(r, s.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &Upload{Key: prefix + "/" + processed.Hash[:16], Variants: datatypes.JSONMap{}}
+	original := processed.Variants[0]
+	if upload.existed, err = s.Storage.Exists(ctx, upload.Key+"/"+original.Name+original.Extension); err != nil {
+		return nil, err
+	}
+
+	for _, variant := range processed.Variants {
+		key := upload.Key + "/" + variant.Name + variant.Extension
+		_, err := s.Storage.Put(ctx, key, bytes.NewReader(variant.Data), storage.PutOptions{
+			ContentType:  variant.ContentType,
+			CacheControl: immutable,
+		})
+		if err != nil {
+			s.discard(ctx, upload, "")
+			return nil, fmt.Errorf("failed to store %s: %w", key, err)
+		}
+
+		url := s.Storage.URL(key)
+		if variant.Name == OriginalVariant {
+			upload.URL = url
+		} else {
+			upload.Variants[variant.Name] = url
+		}
+	}
+	return upload, nil
+}
+
+// Remove deletes every object stored under the image prefix key.
+func (s *Service) Remove(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+	objects, err := s.Storage.List(ctx, strings.TrimSuffix(key, "/")+"/")
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := s.Storage.Delete(ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveUserImage stores the image in r as the imageType image of userID, e.g. "avatar", replacing
+// the previous one and deleting its objects.
+func (s *Service) SaveUserImage(ctx context.Context, userID int, imageType string, r io.Reader) (*sharedModels.UserImage, error) {
+	if !imageTypePattern.MatchString(imageType) {
+		return nil, ErrInvalidImageType
+	}
+
+	upload, err := s.Store(ctx, fmt.Sprintf("images/users/%d/%s", userID, imageType), r)
+	if err != nil {
+		return nil, err
+	}
+
+	var image sharedModels.UserImage
+	var previous string
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND image_type = ?", userID, imageType).First(&image).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		previous = image.ImageKey
+
+		image.UserID = userID
+		image.ImageType = imageType
+		image.ImageURL = upload.URL
+		image.Variants = upload.Variants
+		image.ImageKey = upload.Key
+		return tx.Save(&image).Error
+	})
+	if err != nil {
+		s.discard(ctx, upload, previous)
+		return nil, fmt.Errorf("failed to save user image: %w", err)
+	}
+
+	s.replaced(ctx, previous, upload.Key)
+	return &image, nil
+}
+
+// SaveAdvertisementImage stores the image in r as the image of advertisement id, replacing the previous one.
+func (s *Service) SaveAdvertisementImage(ctx context.Context, id int, r io.Reader) (*sharedModels.Advertisement, error) {
+	var ad sharedModels.Advertisement
+	if err := s.DB.WithContext(ctx).First(&ad, id).Error; err != nil {
+		return nil, err
+	}
+
+	upload, err := s.Store(ctx, fmt.Sprintf("images/advertisements/%d", id), r)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := ad.ImageKey
+	err = s.DB.WithContext(ctx).Model(&ad).Updates(map[string]interface{}{
+		"url_image":      upload.URL,
+		"image_variants": upload.Variants,
+		"image_key":      upload.Key,
+	}).Error
+	if err != nil {
+		s.discard(ctx, upload, previous)
+		return nil, fmt.Errorf("failed to save advertisement image: %w", err)
+	}
+	ad.URLImage = upload.URL
+	ad.ImageVariants = upload.Variants
+	ad.ImageKey = upload.Key
+
+	s.replaced(ctx, previous, upload.Key)
+	return &ad, nil
+}
+
+// discard deletes the objects of an upload that could not be saved. Objects stored before the upload,
+// such as those of previous when the same file is uploaded again, are kept: a saved image uses them.
+func (s *Service) discard(ctx context.Context, upload *Upload, previous string) {
+	if upload.existed || upload.Key == previous {
+		return
+	}
+	if err := s.Remove(ctx, upload.Key); err != nil {
+		log.Printf("⚠️ Failed to delete unsaved image %s: %v", upload.Key, err)
+	}
+}
+
+// replaced deletes the objects of a previous image. Failures are only logged: the new image is saved.
+func (s *Service) replaced(ctx context.Context, previous, current string) {
+	if previous == "" || previous == current {
+		return
+	}
+	if err := s.Remove(ctx, previous); err != nil {
+		log.Printf("⚠️ Failed to delete replaced image %s: %v", previous, err)
+	}
+}
@@ -0,0 +1,131 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/DevdotSP/go-utils/storage"
+)
+
+var errPut = errors.New("put failed")
+
+// failingStorage fails to store the objects whose key ends with suffix.
+type failingStorage struct {
+	storage.Storage
+	suffix string
+}
+
+func (s *failingStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (*storage.Object, error) {
+	if s.suffix != "" && strings.HasSuffix(key, s.suffix) {
+		return nil, errPut
+	}
+	return s.Storage.Put(ctx, key, r, opts)
+}
+
+func newTestService(t *testing.T) (*Service, *failingStorage) {
+	t.Helper()
+	local, err := storage.NewLocal(t.TempDir(), "http://localhost/files", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &failingStorage{Storage: local}
+	return NewService(nil, store), store
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, halves(64, 64)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func objectKeys(t *testing.T, s *Service, key string) []string {
+	t.Helper()
+	objects, err := s.Storage.List(context.Background(), key+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, obj := range objects {
+		keys = append(keys, obj.Key)
+	}
+	return keys
+}
+
+func TestStoreFailureKeepsExistingImage(t *testing.T) {
+	ctx := context.Background()
+	s, store := newTestService(t)
+	data := testPNG(t)
+
+	saved, err := s.Store(ctx, "images/users/1/avatar", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := objectKeys(t, s, saved.Key)
+	if len(before) != 1+len(s.Config.Variants) {
+		t.Fatalf("stored %v, want the original and %d variants", before, len(s.Config.Variants))
+	}
+
+	// Uploading the same file again gives the same keys, still used by the saved image
+	store.suffix = "/thumb.png"
+	if _, err := s.Store(ctx, "images/users/1/avatar", bytes.NewReader(data)); !errors.Is(err, errPut) {
+		t.Fatalf("Store() error = %v, want %v", err, errPut)
+	}
+	if after := objectKeys(t, s, saved.Key); len(after) != len(before) {
+		t.Errorf("objects after failed re-upload = %v, want %v", after, before)
+	}
+}
+
+func TestStoreFailureRemovesNewImage(t *testing.T) {
+	ctx := context.Background()
+	s, store := newTestService(t)
+	store.suffix = "/thumb.png"
+
+	if _, err := s.Store(ctx, "images/users/1/avatar", bytes.NewReader(testPNG(t))); !errors.Is(err, errPut) {
+		t.Fatalf("Store() error = %v, want %v", err, errPut)
+	}
+	if keys := objectKeys(t, s, "images/users/1/avatar"); len(keys) != 0 {
+		t.Errorf("objects after failed upload = %v, want none", keys)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	tests := []struct {
+		name     string
+		existed  bool
+		previous string
+		removed  bool
+	}{
+		{"new image", false, "", true},
+		{"replacing another image", false, "images/users/1/avatar/0123456789abcdef", true},
+		{"same file as the saved image", false, "same", false},
+		{"stored before the upload", true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := newTestService(t)
+			upload, err := s.Store(ctx, "images/users/1/avatar", bytes.NewReader(testPNG(t)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			upload.existed = tt.existed
+			previous := tt.previous
+			if previous == "same" {
+				previous = upload.Key
+			}
+
+			s.discard(ctx, upload, previous)
+			if keys := objectKeys(t, s, upload.Key); (len(keys) == 0) != tt.removed {
+				t.Errorf("objects after discard = %v, removed want %v", keys, tt.removed)
+			}
+		})
+	}
+}
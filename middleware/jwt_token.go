@@ -24,6 +24,9 @@ func JWTAuthMiddleware(c fiber.Ctx) error {
 	// Optionally, store claims in locals for access in the next handlers
 	c.Locals("claims", claims)
 
+	// Expose the user to GORM callbacks that stamp CreatedBy/UpdatedBy/DeletedBy
+	c.SetContext(utils.WithActor(c.Context(), utils.ActorFromClaims(claims)))
+
 	// Proceed to the next handler
 	return c.Next()
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestContextMiddleware stores the request ID, client IP and user agent in the request context
+// so that GORM callbacks (audit log, actor stamping) can read them. Register it before JWTAuthMiddleware.
+func RequestContextMiddleware(c fiber.Ctx) error {
+	requestID := c.Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = utils.GenerateUUID()
+	}
+	c.Set(RequestIDHeader, requestID)
+
+	c.SetContext(utils.WithRequestMeta(c.Context(), utils.RequestMeta{
+		RequestID: requestID,
+		IP:        c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}))
+
+	return c.Next()
+}
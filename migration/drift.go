@@ -0,0 +1,397 @@
+package migration
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/DevdotSP/go-utils/config"
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultSchema is the PostgreSQL schema used by shared-models tables without an explicit prefix.
+const DefaultSchema = "public"
+
+// DriftKind classifies a difference between the models and the live database.
+type DriftKind string
+
+const (
+	DriftMissingTable      DriftKind = "missing_table"
+	DriftMissingColumn     DriftKind = "missing_column"
+	DriftColumnType        DriftKind = "column_type"
+	DriftNullability       DriftKind = "nullability"
+	DriftMissingUnique     DriftKind = "missing_unique"
+	DriftMissingIndex      DriftKind = "missing_index"
+	DriftMissingForeignKey DriftKind = "missing_foreign_key"
+	DriftExtraColumn       DriftKind = "extra_column"
+	DriftExtraTable        DriftKind = "extra_table"
+)
+
+// driftOrder controls the order in which drifts are reported and resolved.
+var driftOrder = map[DriftKind]int{
+	DriftMissingTable:      0,
+	DriftMissingColumn:     1,
+	DriftColumnType:        2,
+	DriftNullability:       3,
+	DriftMissingUnique:     4,
+	DriftMissingIndex:      5,
+	DriftMissingForeignKey: 6,
+	DriftExtraColumn:       7,
+	DriftExtraTable:        8,
+}
+
+// Drift is a single difference between a model schema and the live database.
+type Drift struct {
+	Kind     DriftKind `json:"kind"`
+	Table    string    `json:"table"`
+	Column   string    `json:"column,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Expected string    `json:"expected,omitempty"`
+	Actual   string    `json:"actual,omitempty"`
+
+	schema     *schema.Schema
+	field      *schema.Field
+	index      *schema.Index
+	constraint *schema.Constraint
+}
+
+// Destructive reports whether resolving the drift would drop data.
+func (d Drift) Destructive() bool {
+	return d.Kind == DriftExtraColumn || d.Kind == DriftExtraTable
+}
+
+func (d Drift) String() string {
+	target := d.Table
+	if d.Column != "" {
+		target += "." + d.Column
+	}
+	if d.Name != "" {
+		target += " (" + d.Name + ")"
+	}
+
+	switch {
+	case d.Expected != "" && d.Actual != "":
+		return fmt.Sprintf("%s %s: expected %s, found %s", d.Kind, target, d.Expected, d.Actual)
+	case d.Expected != "":
+		return fmt.Sprintf("%s %s: expected %s", d.Kind, target, d.Expected)
+	default:
+		return fmt.Sprintf("%s %s", d.Kind, target)
+	}
+}
+
+// DriftReport lists every difference found between the registered models and the database.
+type DriftReport struct {
+	Drifts []Drift `json:"drifts"`
+}
+
+// HasDrift reports whether any difference was found.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Drifts) > 0
+}
+
+func (r *DriftReport) String() string {
+	if !r.HasDrift() {
+		return "no schema drift detected"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d schema drift(s) detected:\n", len(r.Drifts))
+	for _, d := range r.Drifts {
+		sb.WriteString("  - " + d.String() + "\n")
+	}
+	return sb.String()
+}
+
+// DetectDrift compares the GORM schemas of the given models with the live PostgreSQL schema.
+func DetectDrift(db *gorm.DB, models ...interface{}) (*DriftReport, error) {
+	report := &DriftReport{}
+	liveSchemas := make(map[string]map[string]*LiveTable)
+	modelTables := make(map[string]bool)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		sch := stmt.Schema
+
+		schemaName, tableName := splitTableName(sch.Table)
+		live, ok := liveSchemas[schemaName]
+		if !ok {
+			var err error
+			if live, err = IntrospectSchema(db, schemaName); err != nil {
+				return nil, err
+			}
+			liveSchemas[schemaName] = live
+		}
+		modelTables[schemaName+"."+tableName] = true
+
+		report.Drifts = append(report.Drifts, diffTable(db, sch, live[tableName])...)
+	}
+
+	for schemaName, live := range liveSchemas {
+		if schemaName == DefaultSchema {
+			continue // public is shared with other applications
+		}
+		for tableName := range live {
+			if !modelTables[schemaName+"."+tableName] {
+				report.Drifts = append(report.Drifts, Drift{Kind: DriftExtraTable, Table: schemaName + "." + tableName})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Drifts, func(i, j int) bool {
+		if driftOrder[report.Drifts[i].Kind] != driftOrder[report.Drifts[j].Kind] {
+			return driftOrder[report.Drifts[i].Kind] < driftOrder[report.Drifts[j].Kind]
+		}
+		return report.Drifts[i].Table < report.Drifts[j].Table
+	})
+
+	return report, nil
+}
+
+// diffTable compares one model schema with its live table; a nil live table is reported as missing.
+func diffTable(db *gorm.DB, sch *schema.Schema, live *LiveTable) []Drift {
+	var drifts []Drift
+
+	if live == nil {
+		drifts = append(drifts, Drift{Kind: DriftMissingTable, Table: sch.Table, schema: sch})
+		live = &LiveTable{Columns: map[string]*LiveColumn{}}
+	} else {
+		for _, field := range sch.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+
+			col, ok := live.Columns[field.DBName]
+			if !ok {
+				drifts = append(drifts, Drift{
+					Kind:     DriftMissingColumn,
+					Table:    sch.Table,
+					Column:   field.DBName,
+					Expected: db.Migrator().FullDataTypeOf(field).SQL,
+					schema:   sch,
+					field:    field,
+				})
+				continue
+			}
+
+			expectedType := db.Dialector.DataTypeOf(field)
+			if !sameType(expectedType, col) {
+				drifts = append(drifts, Drift{
+					Kind:     DriftColumnType,
+					Table:    sch.Table,
+					Column:   field.DBName,
+					Expected: expectedType,
+					Actual:   liveType(col),
+					schema:   sch,
+					field:    field,
+				})
+			}
+
+			expectNotNull := field.NotNull || field.PrimaryKey
+			if expectNotNull == col.Nullable {
+				drifts = append(drifts, Drift{
+					Kind:     DriftNullability,
+					Table:    sch.Table,
+					Column:   field.DBName,
+					Expected: nullability(!expectNotNull),
+					Actual:   nullability(col.Nullable),
+					schema:   sch,
+					field:    field,
+				})
+			}
+		}
+
+		for name := range live.Columns {
+			if sch.LookUpField(name) == nil {
+				drifts = append(drifts, Drift{Kind: DriftExtraColumn, Table: sch.Table, Column: name, schema: sch})
+			}
+		}
+	}
+
+	for _, uni := range sch.ParseUniqueConstraints() {
+		if !hasIndex(live.Indexes, []string{uni.Field.DBName}, true) {
+			drifts = append(drifts, Drift{
+				Kind:   DriftMissingUnique,
+				Table:  sch.Table,
+				Column: uni.Field.DBName,
+				Name:   uni.Name,
+				schema: sch,
+				field:  uni.Field,
+			})
+		}
+	}
+
+	for _, idx := range sch.ParseIndexes() {
+		columns := make([]string, 0, len(idx.Fields))
+		for _, opt := range idx.Fields {
+			columns = append(columns, opt.DBName)
+		}
+		if !hasIndex(live.Indexes, columns, idx.Class == "UNIQUE") {
+			idx := idx
+			drifts = append(drifts, Drift{
+				Kind:     DriftMissingIndex,
+				Table:    sch.Table,
+				Name:     idx.Name,
+				Expected: "(" + strings.Join(columns, ", ") + ")",
+				schema:   sch,
+				index:    &idx,
+			})
+		}
+	}
+
+	for _, rel := range sch.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		// Mirror AutoMigrate: only constraints owned by this table are created with it.
+		constraint := rel.ParseConstraint()
+		if constraint == nil || constraint.Schema != sch || constraint.ReferenceSchema == nil {
+			continue
+		}
+		if !hasForeignKey(live.ForeignKeys, constraint) {
+			drifts = append(drifts, Drift{
+				Kind:       DriftMissingForeignKey,
+				Table:      sch.Table,
+				Name:       constraint.Name,
+				Expected:   "references " + constraint.ReferenceSchema.Table,
+				schema:     sch,
+				constraint: constraint,
+			})
+		}
+	}
+
+	return drifts
+}
+
+func hasIndex(indexes []LiveIndex, columns []string, unique bool) bool {
+	for _, idx := range indexes {
+		if (!unique || idx.IsUnique || idx.IsPrimary) && strings.Join(idx.Columns, ",") == strings.Join(columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasForeignKey(foreignKeys []LiveForeignKey, constraint *schema.Constraint) bool {
+	columns := make([]string, 0, len(constraint.ForeignKeys))
+	for _, field := range constraint.ForeignKeys {
+		columns = append(columns, field.DBName)
+	}
+
+	refSchema, refTable := splitTableName(constraint.ReferenceSchema.Table)
+	for _, fk := range foreignKeys {
+		if fk.Name == constraint.Name {
+			return true
+		}
+		if fk.ReferencedTable == refSchema+"."+refTable && strings.Join(fk.Columns, ",") == strings.Join(columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTableName splits "v1.web_user" into its schema and table parts.
+func splitTableName(table string) (string, string) {
+	if i := strings.Index(table, "."); i != -1 {
+		return table[:i], table[i+1:]
+	}
+	return DefaultSchema, table
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+// typeAliases maps GORM/PostgreSQL shorthand type names to information_schema names.
+var typeAliases = map[string]string{
+	"serial":      "integer",
+	"bigserial":   "bigint",
+	"smallserial": "smallint",
+	"int":         "integer",
+	"int2":        "smallint",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+	"decimal":     "numeric",
+	"float4":      "real",
+	"float8":      "double precision",
+}
+
+// normalizeType returns the canonical base name and size of a declared column type.
+func normalizeType(t string) (string, string) {
+	t = strings.ToLower(strings.TrimSpace(t))
+
+	var size string
+	if i := strings.Index(t, "("); i != -1 {
+		size = strings.Trim(t[i:], "() ")
+		t = strings.TrimSpace(t[:i])
+	}
+	if alias, ok := typeAliases[t]; ok {
+		t = alias
+	}
+	return t, size
+}
+
+func liveType(col *LiveColumn) string {
+	if col.MaxLength > 0 {
+		return fmt.Sprintf("%s(%d)", col.DataType, col.MaxLength)
+	}
+	return col.DataType
+}
+
+func sameType(expected string, col *LiveColumn) bool {
+	expectedBase, expectedSize := normalizeType(expected)
+	actualBase, _ := normalizeType(col.DataType)
+	if expectedBase != actualBase {
+		return false
+	}
+
+	// Only character lengths are compared; numeric and timestamp precision are left alone.
+	if (expectedBase == "character varying" || expectedBase == "character") && expectedSize != "" {
+		return expectedSize == fmt.Sprint(col.MaxLength)
+	}
+	return true
+}
+
+// CheckSchemaDrift compares the registered models with config.DB at startup.
+// SCHEMA_DRIFT_CHECK selects the behaviour: "off", "warn" (default) or "fail".
+func CheckSchemaDrift() *DriftReport {
+	mode := strings.ToLower(utils.GetEnv("SCHEMA_DRIFT_CHECK", "warn"))
+	if mode == "off" {
+		return nil
+	}
+
+	report, err := DetectDrift(config.DB, RegisteredModels()...)
+	if err != nil {
+		if mode == "fail" {
+			log.Fatalf("❌ Schema drift check failed: %v", err)
+		}
+		log.Printf("⚠️ Schema drift check failed: %v", err)
+		return nil
+	}
+
+	if !report.HasDrift() {
+		log.Println("✅ Database schema matches shared-models")
+		return report
+	}
+
+	if mode == "fail" {
+		log.Fatalf("❌ %s", report)
+	}
+	log.Printf("⚠️ %s", report)
+	return report
+}
@@ -0,0 +1,187 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MigrationSQL renders the statements that resolve the drifts in the report.
+// Statements that drop data are written as comments unless destructive is true.
+func MigrationSQL(db *gorm.DB, report *DriftReport, destructive bool) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "-- Schema drift migration generated at %s\n", time.Now().Format(time.DateTime))
+	fmt.Fprintf(&sb, "-- %d drift(s) to resolve\n\n", len(report.Drifts))
+
+	schemas := map[string]bool{}
+	for _, d := range report.Drifts {
+		if d.Kind == DriftMissingTable {
+			if schemaName, _ := splitTableName(d.Table); schemaName != DefaultSchema {
+				schemas[schemaName] = true
+			}
+		}
+	}
+	schemaNames := make([]string, 0, len(schemas))
+	for name := range schemas {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+	for _, name := range schemaNames {
+		fmt.Fprintf(&sb, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", quoteIdent(name))
+	}
+
+	for _, d := range report.Drifts {
+		stmt := driftStatement(db, d)
+		if stmt == "" {
+			continue
+		}
+
+		sb.WriteString("-- " + d.String() + "\n")
+		if d.Destructive() && !destructive {
+			sb.WriteString("-- " + stmt + "\n\n")
+			continue
+		}
+		sb.WriteString(stmt + "\n\n")
+	}
+
+	return sb.String()
+}
+
+// WriteMigrationFile writes the migration for the report to dir as <timestamp>_schema_drift.sql and returns its path.
+func WriteMigrationFile(db *gorm.DB, report *DriftReport, dir string, destructive bool) (string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("failed to create migration folder: %w", err)
+	}
+
+	path := filepath.Join(dir, time.Now().Format("20060102150405")+"_schema_drift.sql")
+	if err := os.WriteFile(path, []byte(MigrationSQL(db, report, destructive)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write migration file: %w", err)
+	}
+	return path, nil
+}
+
+func driftStatement(db *gorm.DB, d Drift) string {
+	table := quoteTable(d.Table)
+
+	switch d.Kind {
+	case DriftMissingTable:
+		return createTableStatement(db, d.schema)
+	case DriftMissingColumn:
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, quoteIdent(d.Column), db.Migrator().FullDataTypeOf(d.field).SQL)
+	case DriftColumnType:
+		target := alterableType(db.Dialector.DataTypeOf(d.field))
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;", table, quoteIdent(d.Column), target, quoteIdent(d.Column), target)
+	case DriftNullability:
+		if d.Expected == "NOT NULL" {
+			return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, quoteIdent(d.Column))
+		}
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, quoteIdent(d.Column))
+	case DriftMissingUnique:
+		return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s);", table, quoteIdent(d.Name), quoteIdent(d.Column))
+	case DriftMissingIndex:
+		return createIndexStatement(d.Table, d.index)
+	case DriftMissingForeignKey:
+		return addForeignKeyStatement(d.Table, d.constraint)
+	case DriftExtraColumn:
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(d.Column))
+	case DriftExtraTable:
+		return fmt.Sprintf("DROP TABLE %s;", table)
+	}
+	return ""
+}
+
+func createTableStatement(db *gorm.DB, sch *schema.Schema) string {
+	var columns, primaryKeys []string
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		columns = append(columns, fmt.Sprintf("\t%s %s", quoteIdent(field.DBName), db.Migrator().FullDataTypeOf(field).SQL))
+		if field.PrimaryKey {
+			primaryKeys = append(primaryKeys, quoteIdent(field.DBName))
+		}
+	}
+	if len(primaryKeys) > 0 {
+		columns = append(columns, fmt.Sprintf("\tPRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", quoteTable(sch.Table), strings.Join(columns, ",\n"))
+}
+
+func createIndexStatement(table string, idx *schema.Index) string {
+	columns := make([]string, 0, len(idx.Fields))
+	for _, opt := range idx.Fields {
+		col := quoteIdent(opt.DBName)
+		if opt.Expression != "" {
+			col = opt.Expression
+		}
+		if opt.Sort != "" {
+			col += " " + opt.Sort
+		}
+		columns = append(columns, col)
+	}
+
+	stmt := "CREATE "
+	if idx.Class != "" {
+		stmt += idx.Class + " "
+	}
+	stmt += fmt.Sprintf("INDEX IF NOT EXISTS %s ON %s", quoteIdent(idx.Name), quoteTable(table))
+	if idx.Type != "" {
+		stmt += " USING " + idx.Type
+	}
+	stmt += " (" + strings.Join(columns, ", ") + ")"
+	if idx.Where != "" {
+		stmt += " WHERE " + idx.Where
+	}
+	return stmt + ";"
+}
+
+func addForeignKeyStatement(table string, constraint *schema.Constraint) string {
+	foreignKeys := make([]string, 0, len(constraint.ForeignKeys))
+	for _, field := range constraint.ForeignKeys {
+		foreignKeys = append(foreignKeys, quoteIdent(field.DBName))
+	}
+	references := make([]string, 0, len(constraint.References))
+	for _, field := range constraint.References {
+		references = append(references, quoteIdent(field.DBName))
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteTable(table), quoteIdent(constraint.Name), strings.Join(foreignKeys, ", "),
+		quoteTable(constraint.ReferenceSchema.Table), strings.Join(references, ", "))
+	if constraint.OnDelete != "" {
+		stmt += " ON DELETE " + constraint.OnDelete
+	}
+	if constraint.OnUpdate != "" {
+		stmt += " ON UPDATE " + constraint.OnUpdate
+	}
+	return stmt + ";"
+}
+
+// alterableType replaces serial pseudo-types, which ALTER COLUMN ... TYPE does not accept.
+func alterableType(t string) string {
+	switch strings.ToLower(t) {
+	case "smallserial":
+		return "smallint"
+	case "serial":
+		return "integer"
+	case "bigserial":
+		return "bigint"
+	}
+	return t
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteTable(table string) string {
+	schemaName, tableName := splitTableName(table)
+	return quoteIdent(schemaName) + "." + quoteIdent(tableName)
+}
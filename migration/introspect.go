@@ -0,0 +1,181 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// LiveColumn describes a column as it exists in the database.
+type LiveColumn struct {
+	Name      string
+	DataType  string // information_schema data_type, with udt_name for user-defined and array types
+	MaxLength int
+	Nullable  bool
+	Default   string
+}
+
+// LiveIndex describes an index (including unique and primary key constraints) as it exists in the database.
+type LiveIndex struct {
+	Name      string
+	Columns   []string
+	IsUnique  bool
+	IsPrimary bool
+}
+
+// LiveForeignKey describes a foreign key constraint as it exists in the database.
+type LiveForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string // schema-qualified, e.g. v1.role
+	ReferencedColumns []string
+}
+
+// LiveTable is the introspected shape of a single table.
+type LiveTable struct {
+	Schema      string
+	Name        string
+	Columns     map[string]*LiveColumn
+	Indexes     []LiveIndex
+	ForeignKeys []LiveForeignKey
+}
+
+// QualifiedName returns the table name prefixed with its schema, matching the shared-models TableName format.
+func (t *LiveTable) QualifiedName() string {
+	return t.Schema + "." + t.Name
+}
+
+// IntrospectSchema reads tables, columns, indexes and foreign keys of a PostgreSQL schema.
+func IntrospectSchema(db *gorm.DB, schemaName string) (map[string]*LiveTable, error) {
+	tables := make(map[string]*LiveTable)
+
+	var tableNames []string
+	if err := db.Raw(`
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = ? AND table_type = 'BASE TABLE'`, schemaName).
+		Scan(&tableNames).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tables in schema %s: %w", schemaName, err)
+	}
+
+	for _, name := range tableNames {
+		tables[name] = &LiveTable{Schema: schemaName, Name: name, Columns: make(map[string]*LiveColumn)}
+	}
+
+	var columns []struct {
+		TableName              string
+		ColumnName             string
+		DataType               string
+		UdtName                string
+		IsNullable             string
+		CharacterMaximumLength *int
+		ColumnDefault          *string
+	}
+	if err := db.Raw(`
+		SELECT table_name, column_name, data_type, udt_name, is_nullable, character_maximum_length, column_default
+		FROM information_schema.columns
+		WHERE table_schema = ?
+		ORDER BY table_name, ordinal_position`, schemaName).
+		Scan(&columns).Error; err != nil {
+		return nil, fmt.Errorf("failed to read columns in schema %s: %w", schemaName, err)
+	}
+
+	for _, col := range columns {
+		table, ok := tables[col.TableName]
+		if !ok {
+			continue // views
+		}
+
+		dataType := col.DataType
+		if dataType == "USER-DEFINED" || dataType == "ARRAY" {
+			dataType = col.UdtName
+		}
+
+		live := &LiveColumn{
+			Name:     col.ColumnName,
+			DataType: dataType,
+			Nullable: col.IsNullable == "YES",
+		}
+		if col.CharacterMaximumLength != nil {
+			live.MaxLength = *col.CharacterMaximumLength
+		}
+		if col.ColumnDefault != nil {
+			live.Default = *col.ColumnDefault
+		}
+		table.Columns[col.ColumnName] = live
+	}
+
+	var indexes []struct {
+		TableName string
+		IndexName string
+		IsUnique  bool
+		IsPrimary bool
+		Columns   string
+	}
+	if err := db.Raw(`
+		SELECT t.relname AS table_name, i.relname AS index_name,
+			ix.indisunique AS is_unique, ix.indisprimary AS is_primary,
+			string_agg(a.attname, ',' ORDER BY k.ord) AS columns
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE n.nspname = ?
+		GROUP BY t.relname, i.relname, ix.indisunique, ix.indisprimary`, schemaName).
+		Scan(&indexes).Error; err != nil {
+		return nil, fmt.Errorf("failed to read indexes in schema %s: %w", schemaName, err)
+	}
+
+	for _, idx := range indexes {
+		if table, ok := tables[idx.TableName]; ok {
+			table.Indexes = append(table.Indexes, LiveIndex{
+				Name:      idx.IndexName,
+				Columns:   strings.Split(idx.Columns, ","),
+				IsUnique:  idx.IsUnique,
+				IsPrimary: idx.IsPrimary,
+			})
+		}
+	}
+
+	var foreignKeys []struct {
+		ConstraintName    string
+		TableName         string
+		ReferencedTable   string
+		Columns           string
+		ReferencedColumns string
+	}
+	if err := db.Raw(`
+		SELECT con.conname AS constraint_name, cl.relname AS table_name,
+			rn.nspname || '.' || ref.relname AS referenced_table,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum) AS columns,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum) AS referenced_columns
+		FROM pg_constraint con
+		JOIN pg_class cl ON cl.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = cl.relnamespace
+		JOIN pg_class ref ON ref.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = ref.relnamespace
+		WHERE con.contype = 'f' AND n.nspname = ?`, schemaName).
+		Scan(&foreignKeys).Error; err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys in schema %s: %w", schemaName, err)
+	}
+
+	for _, fk := range foreignKeys {
+		if table, ok := tables[fk.TableName]; ok {
+			table.ForeignKeys = append(table.ForeignKeys, LiveForeignKey{
+				Name:              fk.ConstraintName,
+				Columns:           strings.Split(fk.Columns, ","),
+				ReferencedTable:   fk.ReferencedTable,
+				ReferencedColumns: strings.Split(fk.ReferencedColumns, ","),
+			})
+		}
+	}
+
+	return tables, nil
+}
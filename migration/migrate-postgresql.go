@@ -1,16 +1,18 @@
 package migration
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/DevdotSP/go-utils/config"
 	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/tenant"
 )
 
-func MigrationTable() {
-
-	err := config.DB.AutoMigrate(
+// RegisteredModels returns the shared-models handled by MigrationTable and the drift check, in migration order.
+func RegisteredModels() []interface{} {
+	return []interface{}{
 		&sharedModels.WebUser{}, // ✅ User table first
 		&sharedModels.Role{}, // ✅ Role (if User depends on Role)
 		&sharedModels.UserLoginHistory{},
@@ -23,13 +25,48 @@ func MigrationTable() {
 		&sharedModels.UserRoleSidebar{},
 		&sharedModels.UserImage{},  // ✅ Move UserImage after User
 		&sharedModels.Notification{}, // ✅ Move Notification after User
+		&sharedModels.NotificationDelivery{},
+		&sharedModels.NotificationReceipt{},
+		&sharedModels.UserDevice{},
 		&sharedModels.PasswordResetToken{},
 		&sharedModels.Advertisement{},
 		&sharedModels.UserExportRequest{},
-	)
+		&sharedModels.AuditLog{},
+		&sharedModels.WebSocketOverflow{},
+		&sharedModels.EmailOutbox{},
+		&sharedModels.EmailDelivery{},
+		&sharedModels.WebhookSubscription{},
+		&sharedModels.WebhookDelivery{},
+		&sharedModels.WebhookAttempt{},
+		&sharedModels.SystemParameter{},
+		&sharedModels.SystemParameterHistory{},
+	}
+}
+
+func MigrationTable() {
+
+	err := config.DB.AutoMigrate(RegisteredModels()...)
 	if err != nil {
 		log.Fatal("❌ AutoMigrate failed:", err)
 	}
 
 	fmt.Println("✅ Database AutoMigration completed successfully!")
-}
\ No newline at end of file
+}
+
+// MigrationTableForTenant creates the schema of tenantID if needed and migrates the registered models into it.
+func MigrationTableForTenant(tenantID string) {
+	if err := tenant.Migrate(config.DB, tenantID, RegisteredModels()...); err != nil {
+		log.Fatalf("❌ AutoMigrate failed for tenant %s: %v", tenantID, err)
+	}
+
+	fmt.Printf("✅ Database AutoMigration completed successfully for tenant %s!\n", tenantID)
+}
+
+// MigrationAllTenants migrates the registered models into every existing tenant schema.
+func MigrationAllTenants() {
+	if err := tenant.MigrateAll(context.Background(), config.DB, RegisteredModels()...); err != nil {
+		log.Fatal("❌ Tenant AutoMigrate failed:", err)
+	}
+
+	fmt.Println("✅ Tenant AutoMigration completed successfully!")
+}
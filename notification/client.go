@@ -0,0 +1,38 @@
+package notification
+
+import (
+	"context"
+	"errors"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+)
+
+// Client is the subset of the FCM API used by Service. *messaging.Client implements it;
+// FakeClient records messages locally for tests and development.
+type Client interface {
+	Send(ctx context.Context, message *messaging.Message) (string, error)
+	SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+}
+
+// NewFCMClient returns the messaging client of app, e.g. config.InitFirebase().
+func NewFCMClient(ctx context.Context, app *firebase.App) (Client, error) {
+	if app == nil {
+		return nil, errors.New("firebase app is not initialized")
+	}
+	return app.Messaging(ctx)
+}
+
+// ErrInvalidToken is returned by FakeClient for tokens listed in InvalidTokens.
+var ErrInvalidToken = errors.New("registration token is not registered")
+
+// IsInvalidToken reports whether err means the registration token will never work again,
+// so it should be removed from storage.
+func IsInvalidToken(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrInvalidToken) || messaging.IsUnregistered(err) || messaging.IsSenderIDMismatch(err)
+}
@@ -0,0 +1,300 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultDeviceTTL is how long a device stays active without being seen. FCM considers tokens
+// unused for about a month stale, so devices are expected to register at least that often.
+const DefaultDeviceTTL = 30 * 24 * time.Hour
+
+// DeviceRequest registers a device of the authenticated user.
+type DeviceRequest struct {
+	Token      string   `json:"token"`
+	Platform   string   `json:"platform"`
+	AppVersion string   `json:"app_version"`
+	Topics     []string `json:"topics"` // Subscribed in addition to TopicAll; omitted keeps the current ones
+}
+
+// ErrTopicNotAllowed rejects topics devices may not subscribe to on their own, see DeviceRegistry.Topics.
+var ErrTopicNotAllowed = errors.New("topic is not allowed")
+
+// RefreshTokenRequest replaces a token rotated by the FCM SDK.
+type RefreshTokenRequest struct {
+	OldToken string `json:"old_token"`
+	NewToken string `json:"new_token"`
+}
+
+// DeviceRegistry stores the push targets of users and keeps their topic subscriptions in sync.
+type DeviceRegistry struct {
+	DB      *gorm.DB
+	Service *Service
+	TTL     time.Duration
+
+	// Topics lists the topics devices may subscribe to on their own, besides TopicAll which they always
+	// are; a key ending in "*" matches every topic with that prefix. Other topics are only reachable
+	// through Service.Subscribe.
+	Topics []string
+}
+
+// NewDeviceRegistry returns a registry for the devices of s, and makes s fan out to them
+// and prune the tokens FCM rejects.
+func NewDeviceRegistry(s *Service) *DeviceRegistry {
+	r := &DeviceRegistry{DB: s.DB, Service: s, TTL: DefaultDeviceTTL}
+	s.Devices = r
+	s.Pruner = r
+	return r
+}
+
+// Register adds or updates the device of userID and subscribes it to its topics. Without req.Topics,
+// e.g. on a heartbeat, a known device keeps its topics. A token previously registered by another user
+// is moved to userID, without the topics of that user.
+func (r *DeviceRegistry) Register(ctx context.Context, userID int, req DeviceRequest) (*sharedModels.UserDevice, error) {
+	if req.Token == "" {
+		return nil, errors.New("token is required")
+	}
+	for _, topic := range req.Topics {
+		if topic != TopicAll && !r.allowed(topic) {
+			return nil, fmt.Errorf("%w: %s", ErrTopicNotAllowed, topic)
+		}
+	}
+
+	var device sharedModels.UserDevice
+	var previous, topics []string
+
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token = ?", req.Token).First(&device).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		previous = device.Topics
+
+		topics = req.Topics
+		if topics == nil && device.UserID == userID {
+			topics = previous
+		}
+		topics = unique(append([]string{TopicAll}, topics...))
+
+		device.UserID = userID
+		device.Token = req.Token
+		device.Platform = req.Platform
+		device.AppVersion = req.AppVersion
+		device.Topics = datatypes.NewJSONSlice(topics)
+		device.LastSeenAt = time.Now()
+		return tx.Save(&device).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	if err := r.syncTopics(ctx, req.Token, previous, topics); err != nil {
+		return &device, err
+	}
+	return &device, nil
+}
+
+// Unregister removes a device of userID, e.g. on logout, and unsubscribes it from its topics.
+// It fails with gorm.ErrRecordNotFound when the user has no such device.
+func (r *DeviceRegistry) Unregister(ctx context.Context, userID int, token string) error {
+	var device sharedModels.UserDevice
+	result := r.DB.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("user_id = ? AND token = ?", userID, token).
+		Delete(&device)
+	if result.Error != nil {
+		return fmt.Errorf("failed to unregister device: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return r.syncTopics(ctx, token, device.Topics, nil)
+}
+
+// Refresh moves the device of userID from oldToken to newToken, keeping its topics.
+func (r *DeviceRegistry) Refresh(ctx context.Context, userID int, oldToken, newToken string) (*sharedModels.UserDevice, error) {
+	if newToken == "" {
+		return nil, errors.New("new token is required")
+	}
+
+	var device sharedModels.UserDevice
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND token = ?", userID, oldToken).First(&device).Error; err != nil {
+			return err
+		}
+		// The new token may already have been registered on its own
+		if err := tx.Where("token = ? AND id <> ?", newToken, device.ID).Delete(&sharedModels.UserDevice{}).Error; err != nil {
+			return err
+		}
+
+		device.Token = newToken
+		device.LastSeenAt = time.Now()
+		return tx.Save(&device).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh device token: %w", err)
+	}
+
+	return &device, r.syncTopics(ctx, newToken, nil, device.Topics)
+}
+
+// SubscribeTopic subscribes the device of userID holding token to topic.
+func (r *DeviceRegistry) SubscribeTopic(ctx context.Context, userID int, token, topic string) error {
+	return r.updateTopic(ctx, userID, token, topic, true)
+}
+
+// UnsubscribeTopic removes the device of userID holding token from topic, whether allowed or not.
+func (r *DeviceRegistry) UnsubscribeTopic(ctx context.Context, userID int, token, topic string) error {
+	return r.updateTopic(ctx, userID, token, topic, false)
+}
+
+// updateTopic adds topic to, or removes it from, the topics of the device of userID holding token,
+// failing with gorm.ErrRecordNotFound when the user has no such device.
+func (r *DeviceRegistry) updateTopic(ctx context.Context, userID int, token, topic string, subscribe bool) error {
+	// Any topic may be left, including one added through Service.Subscribe
+	if subscribe && !r.allowed(topic) {
+		return fmt.Errorf("%w: %s", ErrTopicNotAllowed, topic)
+	}
+
+	var device sharedModels.UserDevice
+	var previous []string
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND token = ?", userID, token).
+			First(&device).Error
+		if err != nil {
+			return err
+		}
+		previous = device.Topics
+
+		topics := make([]string, 0, len(previous)+1)
+		for _, current := range previous {
+			if current != topic {
+				topics = append(topics, current)
+			}
+		}
+		if subscribe {
+			topics = append(topics, topic)
+		}
+		device.Topics = datatypes.NewJSONSlice(topics)
+		return tx.Model(&device).Update("topics", device.Topics).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update device topics: %w", err)
+	}
+	return r.syncTopics(ctx, token, previous, device.Topics)
+}
+
+// allowed reports whether devices may subscribe to topic on their own.
+func (r *DeviceRegistry) allowed(topic string) bool {
+	if topic == TopicAll {
+		return false
+	}
+	for _, pattern := range r.Topics {
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); pattern == topic || (isPrefix && strings.HasPrefix(topic, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveTokens returns the tokens of the devices of userID seen within TTL.
+func (r *DeviceRegistry) ActiveTokens(ctx context.Context, userID int) ([]string, error) {
+	var tokens []string
+	err := r.DB.WithContext(ctx).Model(&sharedModels.UserDevice{}).
+		Where("user_id = ? AND last_seen_at >= ?", userID, time.Now().Add(-r.ttl())).
+		Pluck("token", &tokens).Error
+	return tokens, err
+}
+
+// TopicTokens returns the tokens of the devices subscribed to topic seen within TTL, leaving out the
+// devices of the users in exclude.
+func (r *DeviceRegistry) TopicTokens(ctx context.Context, topic string, exclude []int) ([]string, error) {
+	filter, err := json.Marshal([]string{topic})
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.DB.WithContext(ctx).Model(&sharedModels.UserDevice{}).
+		Where("topics @> ?::jsonb AND last_seen_at >= ?", string(filter), time.Now().Add(-r.ttl()))
+	if len(exclude) > 0 {
+		query = query.Where("user_id NOT IN ?", exclude)
+	}
+
+	var tokens []string
+	err = query.Pluck("token", &tokens).Error
+	return tokens, err
+}
+
+// ExpireStale deletes devices not seen within TTL, unsubscribes them from their topics and returns
+// how many were removed. Run it periodically, e.g. from a daily job.
+func (r *DeviceRegistry) ExpireStale(ctx context.Context) (int64, error) {
+	var devices []sharedModels.UserDevice
+	result := r.DB.WithContext(ctx).Clauses(clause.Returning{}).
+		Where("last_seen_at < ?", time.Now().Add(-r.ttl())).
+		Delete(&devices)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	// The rows are gone either way: a failed unsubscribe only leaves a token FCM drops once stale
+	for _, device := range devices {
+		if err := r.syncTopics(ctx, device.Token, device.Topics, nil); err != nil {
+			log.Printf("⚠️ Failed to unsubscribe expired device %d: %v", device.ID, err)
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// PruneTokens implements TokenPruner by deleting the devices holding tokens.
+func (r *DeviceRegistry) PruneTokens(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.DB.WithContext(ctx).Where("token IN ?", tokens).Delete(&sharedModels.UserDevice{}).Error
+}
+
+func (r *DeviceRegistry) ttl() time.Duration {
+	if r.TTL <= 0 {
+		return DefaultDeviceTTL
+	}
+	return r.TTL
+}
+
+// syncTopics subscribes token to the topics it gained and unsubscribes it from those it lost.
+func (r *DeviceRegistry) syncTopics(ctx context.Context, token string, previous, current []string) error {
+	if r.Service == nil || r.Service.Client == nil {
+		return nil
+	}
+
+	keep := make(map[string]bool, len(current))
+	for _, topic := range current {
+		keep[topic] = true
+	}
+	had := make(map[string]bool, len(previous))
+	for _, topic := range previous {
+		had[topic] = true
+		if !keep[topic] {
+			if err := r.Service.Unsubscribe(ctx, []string{token}, topic); err != nil {
+				return err
+			}
+		}
+	}
+	for _, topic := range current {
+		if !had[topic] {
+			if err := r.Service.Subscribe(ctx, []string{token}, topic); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// FakeClient is an in-memory Client. Messages are recorded instead of sent, tokens in
+// InvalidTokens fail with ErrInvalidToken and Err, when set, fails every call.
+type FakeClient struct {
+	mu            sync.Mutex
+	Sent          []*messaging.Message
+	Subscriptions map[string]map[string]bool // topic => tokens
+	InvalidTokens map[string]bool
+	Err           error
+	nextID        int
+}
+
+// NewFakeClient returns an empty FakeClient.
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		Subscriptions: make(map[string]map[string]bool),
+		InvalidTokens: make(map[string]bool),
+	}
+}
+
+func (f *FakeClient) Send(ctx context.Context, message *messaging.Message) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.send(message)
+}
+
+func (f *FakeClient) send(message *messaging.Message) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if message.Token != "" && f.InvalidTokens[message.Token] {
+		return "", fmt.Errorf("%w: %s", ErrInvalidToken, message.Token)
+	}
+	f.Sent = append(f.Sent, message)
+	f.nextID++
+	return fmt.Sprintf("fake-message-%d", f.nextID), nil
+}
+
+func (f *FakeClient) SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	batch := &messaging.BatchResponse{}
+	for _, token := range message.Tokens {
+		id, err := f.send(&messaging.Message{
+			Token:        token,
+			Data:         message.Data,
+			Notification: message.Notification,
+			Android:      message.Android,
+			Webpush:      message.Webpush,
+			APNS:         message.APNS,
+			FCMOptions:   message.FCMOptions,
+		})
+		if err != nil {
+			batch.FailureCount++
+		} else {
+			batch.SuccessCount++
+		}
+		batch.Responses = append(batch.Responses, &messaging.SendResponse{Success: err == nil, MessageID: id, Error: err})
+	}
+	return batch, nil
+}
+
+func (f *FakeClient) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return f.manageTopic(tokens, topic, true)
+}
+
+func (f *FakeClient) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return f.manageTopic(tokens, topic, false)
+}
+
+func (f *FakeClient) manageTopic(tokens []string, topic string, subscribe bool) (*messaging.TopicManagementResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	resp := &messaging.TopicManagementResponse{}
+	for i, token := range tokens {
+		if f.InvalidTokens[token] {
+			resp.FailureCount++
+			resp.Errors = append(resp.Errors, &messaging.ErrorInfo{Index: i, Reason: ErrInvalidToken.Error()})
+			continue
+		}
+		if f.Subscriptions[topic] == nil {
+			f.Subscriptions[topic] = make(map[string]bool)
+		}
+		if subscribe {
+			f.Subscriptions[topic][token] = true
+		} else {
+			delete(f.Subscriptions[topic], token)
+		}
+		resp.SuccessCount++
+	}
+	return resp, nil
+}
+
+// Messages returns a copy of the recorded messages.
+func (f *FakeClient) Messages() []*messaging.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*messaging.Message(nil), f.Sent...)
+}
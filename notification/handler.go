@@ -0,0 +1,157 @@
+package notification
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// SubscribeHandler subscribes the token in a SubscriptionRequest body to its topic. With a DeviceRegistry,
+// the token must be a device of the authenticated user and the topic allowed by DeviceRegistry.Topics.
+func SubscribeHandler(s *Service) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return handleSubscription(c, s, true)
+	}
+}
+
+// UnsubscribeHandler removes the token in a SubscriptionRequest body from its topic. With a DeviceRegistry,
+// the token must be a device of the authenticated user.
+func UnsubscribeHandler(s *Service) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return handleSubscription(c, s, false)
+	}
+}
+
+func handleSubscription(c fiber.Ctx, s *Service, subscribe bool) error {
+	var req sharedModels.SubscriptionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid input")
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	req.Topic = strings.TrimSpace(req.Topic)
+	if req.Token == "" || req.Topic == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Token and topic are required")
+	}
+
+	if s.Devices == nil {
+		update := s.Unsubscribe
+		if subscribe {
+			update = s.Subscribe
+		}
+		if err := update(c.Context(), []string{req.Token}, req.Topic); err != nil {
+			return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+		}
+		return helper.JSONResponse(c, respcode.SUC_CODE_200, respcode.SUC_CODE_200_MSG)
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+	update := s.Devices.UnsubscribeTopic
+	if subscribe {
+		update = s.Devices.SubscribeTopic
+	}
+	err := update(c.Context(), userID, req.Token, req.Topic)
+	switch {
+	case errors.Is(err, ErrTopicNotAllowed):
+		return helper.JSONResponse(c, respcode.ERR_CODE_403, "Topic is not allowed")
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case err != nil:
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_200, respcode.SUC_CODE_200_MSG)
+}
+
+// RegisterRoutes mounts the device endpoints of the authenticated user on router, behind JWTAuthMiddleware:
+// POST / registers (or heartbeats) a device, PUT /refresh rotates its token and DELETE / unregisters it.
+func (r *DeviceRegistry) RegisterRoutes(router fiber.Router) {
+	router.Post("/", r.RegisterHandler)
+	router.Put("/refresh", r.RefreshHandler)
+	router.Delete("/", r.UnregisterHandler)
+}
+
+// RegisterHandler registers the device in a DeviceRequest body for the authenticated user.
+func (r *DeviceRegistry) RegisterHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var req DeviceRequest
+	if err := c.Bind().Body(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Token is required")
+	}
+	req.Token = strings.TrimSpace(req.Token)
+
+	device, err := r.Register(c.Context(), userID, req)
+	if errors.Is(err, ErrTopicNotAllowed) {
+		return helper.JSONResponse(c, respcode.ERR_CODE_403, "Topic is not allowed")
+	}
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_200, respcode.SUC_CODE_200_MSG, device)
+}
+
+// RefreshHandler replaces the token of a device of the authenticated user.
+func (r *DeviceRegistry) RefreshHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var req RefreshTokenRequest
+	if err := c.Bind().Body(&req); err != nil || req.OldToken == "" || req.NewToken == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Old and new token are required")
+	}
+
+	device, err := r.Refresh(c.Context(), userID, req.OldToken, req.NewToken)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	}
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, device)
+}
+
+// UnregisterHandler removes the device whose token is in the body, e.g. on logout.
+func (r *DeviceRegistry) UnregisterHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	var req DeviceRequest
+	if err := c.Bind().Body(&req); err != nil || req.Token == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Token is required")
+	}
+
+	err := r.Unregister(c.Context(), userID, req.Token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	}
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_DELETE, respcode.SUC_CODE_DELETE_MSG)
+}
+
+// currentUserID returns the user set in the request context by JWTAuthMiddleware.
+func currentUserID(c fiber.Ctx) (int, bool) {
+	userID, err := strconv.Atoi(utils.ActorFromContext(c.Context()))
+	return userID, err == nil && userID > 0
+}
@@ -0,0 +1,217 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Inbox filters
+const (
+	FilterActive   = ""         // Everything not archived
+	FilterUnread   = "unread"   // Unread and not archived
+	FilterArchived = "archived" // Archived only
+	FilterAll      = "all"      // Including archived
+)
+
+// InboxItem is a notification as seen by one user. IsRead and ArchivedAt come from the notification
+// for direct notifications and from the user's receipt for broadcasts.
+type InboxItem struct {
+	ID         int               `json:"id"`
+	UserType   string            `json:"user_type"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Topic      *string           `json:"topic,omitempty"`
+	Broadcast  bool              `json:"broadcast"`
+	Data       datatypes.JSONMap `json:"data,omitempty"`
+	IsRead     bool              `json:"is_read"`
+	ArchivedAt *time.Time        `json:"archived_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// InboxPage is one page of an inbox. Pass NextCursor back as the cursor to get the next page;
+// it is nil on the last page.
+type InboxPage struct {
+	Items      []InboxItem `json:"items"`
+	NextCursor *int        `json:"next_cursor"`
+}
+
+// Inbox serves the in-app notifications of a user: the notifications addressed to them plus the
+// broadcasts they receive, i.e. TargetAll notifications and those on a topic one of their devices is subscribed to.
+type Inbox struct {
+	DB *gorm.DB
+}
+
+// NewInbox returns an Inbox reading notifications from db.
+func NewInbox(db *gorm.DB) *Inbox {
+	return &Inbox{DB: db}
+}
+
+func table(model interface{ TableName() string }) clause.Table {
+	return clause.Table{Name: model.TableName()}
+}
+
+// visible is the FROM and WHERE shared by the inbox queries: the notifications of userID (n), joined
+// with the user's receipt (r), without broadcasts the user deleted.
+func visible(userID int) (string, []interface{}) {
+	sql := `FROM ? n
+		LEFT JOIN ? r ON r.notification_id = n.id AND r.user_id = ?
+		WHERE r.deleted_at IS NULL AND (n.user_id = ? OR (n.user_id IS NULL AND (n.target_all OR EXISTS (
+			SELECT 1 FROM ? d WHERE d.user_id = ? AND d.topics @> jsonb_build_array(n.topic)
+		))))`
+	return sql, []interface{}{
+		table(sharedModels.Notification{}), table(sharedModels.NotificationReceipt{}), userID,
+		userID, table(sharedModels.UserDevice{}), userID,
+	}
+}
+
+const (
+	readExpr     = "CASE WHEN n.user_id IS NULL THEN r.read_at IS NOT NULL ELSE n.is_read END"
+	archivedExpr = "COALESCE(r.archived_at, n.archived_at)"
+)
+
+// List returns up to limit notifications older than cursor (0 for the newest), newest first.
+func (in *Inbox) List(ctx context.Context, userID int, filter string, cursor, limit int) (*InboxPage, error) {
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	from, vars := visible(userID)
+	sql := `SELECT n.id, n.user_type, n.title, n.body, n.topic, n.user_id IS NULL AS broadcast, n.data, n.created_at, ` +
+		readExpr + ` AS is_read, ` + archivedExpr + ` AS archived_at ` + from
+
+	switch filter {
+	case FilterUnread:
+		sql += ` AND NOT (` + readExpr + `) AND ` + archivedExpr + ` IS NULL`
+	case FilterArchived:
+		sql += ` AND ` + archivedExpr + ` IS NOT NULL`
+	case FilterAll:
+	default:
+		sql += ` AND ` + archivedExpr + ` IS NULL`
+	}
+	if cursor > 0 {
+		sql += ` AND n.id < ?`
+		vars = append(vars, cursor)
+	}
+	sql += ` ORDER BY n.id DESC LIMIT ?`
+	vars = append(vars, limit+1)
+
+	var items []InboxItem
+	if err := in.DB.WithContext(ctx).Raw(sql, vars...).Scan(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to load inbox: %w", err)
+	}
+
+	page := &InboxPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next := page.Items[limit-1].ID
+		page.NextCursor = &next
+	}
+	if page.Items == nil {
+		page.Items = []InboxItem{}
+	}
+	return page, nil
+}
+
+// UnreadCount returns the number of unread, not archived notifications of userID.
+func (in *Inbox) UnreadCount(ctx context.Context, userID int) (int64, error) {
+	from, vars := visible(userID)
+	sql := `SELECT count(*) ` + from + ` AND NOT (` + readExpr + `) AND ` + archivedExpr + ` IS NULL`
+
+	var count int64
+	err := in.DB.WithContext(ctx).Raw(sql, vars...).Scan(&count).Error
+	return count, err
+}
+
+// MarkRead marks the given notifications of userID as read.
+func (in *Inbox) MarkRead(ctx context.Context, userID int, ids ...int) error {
+	return in.apply(ctx, userID, ids, map[string]interface{}{"is_read": true}, "read_at")
+}
+
+// MarkAllRead marks every notification of userID as read, including all broadcasts the user receives.
+func (in *Inbox) MarkAllRead(ctx context.Context, userID int) error {
+	return in.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&sharedModels.Notification{}).
+			Where("user_id = ? AND is_read = ?", userID, false).
+			Update("is_read", true).Error
+		if err != nil {
+			return err
+		}
+
+		from, vars := visible(userID)
+		return tx.Exec(`INSERT INTO ? (notification_id, user_id, read_at, updated_at)
+			SELECT n.id, ?, now(), now() `+from+` AND n.user_id IS NULL AND r.read_at IS NULL
+			ON CONFLICT (notification_id, user_id) DO UPDATE SET read_at = EXCLUDED.read_at, updated_at = EXCLUDED.updated_at`,
+			append([]interface{}{table(sharedModels.NotificationReceipt{}), userID}, vars...)...).Error
+	})
+}
+
+// Archive moves the given notifications of userID out of the active inbox.
+func (in *Inbox) Archive(ctx context.Context, userID int, ids ...int) error {
+	return in.apply(ctx, userID, ids, map[string]interface{}{"archived_at": time.Now()}, "archived_at")
+}
+
+// Unarchive moves the given notifications of userID back to the active inbox.
+func (in *Inbox) Unarchive(ctx context.Context, userID int, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return in.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&sharedModels.Notification{}).
+			Where("id IN ? AND user_id = ?", ids, userID).
+			Update("archived_at", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&sharedModels.NotificationReceipt{}).
+			Where("notification_id IN ? AND user_id = ?", ids, userID).
+			Update("archived_at", nil).Error
+	})
+}
+
+// Delete removes the given notifications from the inbox of userID. Direct notifications are deleted;
+// broadcasts are only hidden for this user.
+func (in *Inbox) Delete(ctx context.Context, userID int, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return in.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ? AND user_id = ?", ids, userID).Delete(&sharedModels.Notification{}).Error; err != nil {
+			return err
+		}
+		return in.upsertReceipts(tx, userID, ids, "deleted_at")
+	})
+}
+
+// apply sets updates on the direct notifications among ids and stamps column on the receipts of the broadcasts.
+func (in *Inbox) apply(ctx context.Context, userID int, ids []int, updates map[string]interface{}, column string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return in.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&sharedModels.Notification{}).Where("id IN ? AND user_id = ?", ids, userID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return in.upsertReceipts(tx, userID, ids, column)
+	})
+}
+
+// upsertReceipts stamps column with the current time on the receipts of userID for the broadcasts among ids
+// the user can see, keeping the first timestamp when it is already set.
+func (in *Inbox) upsertReceipts(tx *gorm.DB, userID int, ids []int, column string) error {
+	from, vars := visible(userID)
+	col := clause.Column{Name: column}
+	sql := `INSERT INTO ? AS rc (notification_id, user_id, ?, updated_at)
+		SELECT n.id, ?, now(), now() ` + from + ` AND n.user_id IS NULL AND n.id IN ?
+		ON CONFLICT (notification_id, user_id) DO UPDATE SET ? = COALESCE(?, EXCLUDED.?), updated_at = EXCLUDED.updated_at`
+
+	args := []interface{}{table(sharedModels.NotificationReceipt{}), col, userID}
+	args = append(args, vars...)
+	args = append(args, ids, col, clause.Column{Table: "rc", Name: column}, col)
+	return tx.Exec(sql, args...).Error
+}
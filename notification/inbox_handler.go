@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/gofiber/fiber/v3"
+)
+
+// IDsRequest selects the notifications of a bulk inbox action.
+type IDsRequest struct {
+	IDs []int `json:"ids"`
+}
+
+// RegisterRoutes mounts the inbox of the authenticated user on router, behind JWTAuthMiddleware.
+// Bulk actions take an IDsRequest body; the /:id routes act on a single notification.
+func (in *Inbox) RegisterRoutes(router fiber.Router) {
+	router.Get("/", in.ListHandler)
+	router.Get("/unread-count", in.UnreadCountHandler)
+	router.Put("/read-all", in.MarkAllReadHandler)
+	router.Put("/read", in.action(in.MarkRead))
+	router.Put("/archive", in.action(in.Archive))
+	router.Put("/unarchive", in.action(in.Unarchive))
+	router.Delete("/", in.action(in.Delete))
+	router.Put("/:id/read", in.action(in.MarkRead))
+	router.Put("/:id/archive", in.action(in.Archive))
+	router.Put("/:id/unarchive", in.action(in.Unarchive))
+	router.Delete("/:id", in.action(in.Delete))
+}
+
+// ListHandler returns one page of the inbox. Supports ?filter=unread|archived|all&cursor=&limit=.
+func (in *Inbox) ListHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	page, err := in.List(c.Context(), userID, c.Query("filter"), fiber.Query[int](c, "cursor", 0), fiber.Query[int](c, "limit", 20))
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, page)
+}
+
+// UnreadCountHandler returns {"unread": n}.
+func (in *Inbox) UnreadCountHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	count, err := in.UnreadCount(c.Context(), userID)
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, fiber.Map{"unread": count})
+}
+
+// MarkAllReadHandler marks the whole inbox as read.
+func (in *Inbox) MarkAllReadHandler(c fiber.Ctx) error {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+	}
+
+	if err := in.MarkAllRead(c.Context(), userID); err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG)
+}
+
+// action adapts an inbox method to a handler taking the ids from :id or an IDsRequest body.
+func (in *Inbox) action(fn func(ctx context.Context, userID int, ids ...int) error) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return helper.JSONResponse(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG)
+		}
+
+		var req IDsRequest
+		if raw := c.Params("id"); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+			}
+			req.IDs = []int{id}
+		} else if err := c.Bind().Body(&req); err != nil || len(req.IDs) == 0 {
+			return helper.JSONResponse(c, respcode.ERR_CODE_400, "IDs are required")
+		}
+
+		if err := fn(c.Context(), userID, req.IDs...); err != nil {
+			return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+		}
+
+		return helper.JSONResponse(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG)
+	}
+}
@@ -0,0 +1,194 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DevdotSP/go-utils/config"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+)
+
+// EventNotification is the envelope type of notifications pushed over WebSocket. Clients must ack it by ID.
+const EventNotification = "notification"
+
+// BroadcastRoom is the hub room of TargetAll notifications; every connection joins it.
+var BroadcastRoom = "broadcast"
+
+// TopicRoom returns the hub room of topic notifications. Clients join it with a subscribe message.
+func TopicRoom(topic string) string {
+	return "topic:" + topic
+}
+
+// Realtime delivers notifications over the WebSocket hub first and through FCM only to users no live
+// socket acknowledged them for. The hub must run a config.Router so client acks are processed.
+//
+// Sends return once the notification is stored and pushed to the sockets: waiting for the acks and
+// the FCM fallback run in the background, and record the deliveries and status of the notification
+// when done. Call Wait before exiting so they are not cut short.
+type Realtime struct {
+	Service *Service
+	Hub     *config.Hub
+	Policy  config.AckPolicy // Ack wait and resends for a single user
+	Window  time.Duration    // How long broadcasts collect acks, defaults to the policy timeout
+	Timeout time.Duration    // Bounds each background delivery, defaults to one minute
+
+	wg sync.WaitGroup
+}
+
+// NewRealtime returns a Realtime sending through s and hub, and makes every new connection of hub
+// join BroadcastRoom. Call it before serving connections.
+func NewRealtime(s *Service, hub *config.Hub) *Realtime {
+	onConnect := hub.OnConnect
+	hub.OnConnect = func(c *config.Client) {
+		c.Join(BroadcastRoom)
+		if onConnect != nil {
+			onConnect(c)
+		}
+	}
+	return &Realtime{Service: s, Hub: hub, Policy: config.AckPolicy{Timeout: 3 * time.Second, Attempts: 2}}
+}
+
+// SendToUser stores msg for userID and pushes it to the user's sockets. When none acknowledges it in time,
+// it is multicast to the user's devices through FCM instead.
+func (r *Realtime) SendToUser(ctx context.Context, msg Message, userID int) (*sharedModels.Notification, error) {
+	msg.UserID = &userID
+	n := newNotification(msg)
+	return r.send(ctx, n, func(ctx context.Context) []sharedModels.NotificationDelivery {
+		acked, err := r.Hub.DeliverToUser(ctx, strconv.Itoa(userID), r.envelope(n), r.Policy)
+		if acked {
+			return []sharedModels.NotificationDelivery{socketDelivery(userID)}
+		}
+		if err != nil && ctx.Err() != nil {
+			return []sharedModels.NotificationDelivery{failedDelivery(userID, ChannelWebSocket, err)}
+		}
+
+		if r.Service.Devices == nil {
+			return nil
+		}
+		tokens, err := r.Service.Devices.ActiveTokens(ctx, userID)
+		if err != nil {
+			return []sharedModels.NotificationDelivery{failedDelivery(userID, ChannelFCM, err)}
+		}
+		return forUser(r.Service.sendTokens(ctx, n, tokens), userID)
+	})
+}
+
+// SendToTopic stores msg and publishes it to the TopicRoom of topic, then through FCM to the subscribers
+// of topic that did not acknowledge it.
+func (r *Realtime) SendToTopic(ctx context.Context, msg Message, topic string) (*sharedModels.Notification, error) {
+	n := newNotification(msg)
+	n.Topic = &topic
+	return r.send(ctx, n, func(ctx context.Context) []sharedModels.NotificationDelivery {
+		deliveries, acked := r.broadcast(ctx, n, TopicRoom(topic))
+		return append(deliveries, r.fallback(ctx, n, topic, acked)...)
+	})
+}
+
+// SendToAll stores msg as a broadcast and publishes it to BroadcastRoom, then through FCM to the
+// devices of the users that did not acknowledge it.
+func (r *Realtime) SendToAll(ctx context.Context, msg Message) (*sharedModels.Notification, error) {
+	msg.UserID = nil
+	n := newNotification(msg)
+	n.TargetAll = true
+	return r.send(ctx, n, func(ctx context.Context) []sharedModels.NotificationDelivery {
+		deliveries, acked := r.broadcast(ctx, n, BroadcastRoom)
+		return append(deliveries, r.fallback(ctx, n, TopicAll, acked)...)
+	})
+}
+
+// Wait blocks until the background deliveries are done, e.g. on shutdown.
+func (r *Realtime) Wait() {
+	r.wg.Wait()
+}
+
+// send stores n and runs deliver in the background, detached from the cancellation of ctx and bounded
+// by Timeout. It returns a copy of n, as the background delivery updates n.
+func (r *Realtime) send(ctx context.Context, n *sharedModels.Notification, deliver func(ctx context.Context) []sharedModels.NotificationDelivery) (*sharedModels.Notification, error) {
+	if err := r.Service.save(ctx, n); err != nil {
+		return nil, err
+	}
+	stored := *n
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+		if err := r.Service.record(ctx, n, deliver(ctx)); err != nil {
+			log.Printf("⚠️ Realtime notification %d: %v", n.ID, err)
+		}
+	}()
+	return &stored, nil
+}
+
+// broadcast publishes n to room and returns a delivery for every user that acknowledged it, and their IDs.
+func (r *Realtime) broadcast(ctx context.Context, n *sharedModels.Notification, room string) ([]sharedModels.NotificationDelivery, []int) {
+	window := r.Window
+	if window <= 0 {
+		window = r.Policy.Timeout
+	}
+	if window <= 0 {
+		window = 3 * time.Second
+	}
+
+	users, _ := r.Hub.DeliverToRoom(ctx, room, r.envelope(n), window)
+	deliveries := make([]sharedModels.NotificationDelivery, 0, len(users))
+	acked := make([]int, 0, len(users))
+	for _, user := range users {
+		if userID, err := strconv.Atoi(user); err == nil {
+			deliveries = append(deliveries, socketDelivery(userID))
+			acked = append(acked, userID)
+		}
+	}
+	return deliveries, acked
+}
+
+// fallback multicasts n to the devices subscribed to topic, leaving out the users in acked. Without a
+// device registry it publishes to the FCM topic, which reaches acknowledged users again: clients then
+// drop the copy whose notification_id they already received over WebSocket.
+func (r *Realtime) fallback(ctx context.Context, n *sharedModels.Notification, topic string, acked []int) []sharedModels.NotificationDelivery {
+	if r.Service.Devices == nil {
+		return r.Service.sendTopic(ctx, n, topic)
+	}
+	tokens, err := r.Service.Devices.TopicTokens(ctx, topic, acked)
+	if err != nil {
+		return []sharedModels.NotificationDelivery{newDelivery("", topic, "", err)}
+	}
+	return r.Service.sendTokens(ctx, n, tokens)
+}
+
+// envelope wraps n with the notification ID as event ID, so resends and the FCM copy can be deduplicated.
+func (r *Realtime) envelope(n *sharedModels.Notification) config.Envelope {
+	topic := ""
+	if n.Topic != nil {
+		topic = *n.Topic
+	}
+	// A Notification always encodes
+	env, _ := config.NewEnvelope(EventNotification, topic, n)
+	env.ID = "notification:" + strconv.Itoa(n.ID)
+	return env
+}
+
+func socketDelivery(userID int) sharedModels.NotificationDelivery {
+	return sharedModels.NotificationDelivery{UserID: &userID, Channel: ChannelWebSocket, Status: DeliverySent}
+}
+
+func failedDelivery(userID int, channel string, err error) sharedModels.NotificationDelivery {
+	return sharedModels.NotificationDelivery{UserID: &userID, Channel: channel, Status: DeliveryFailed, Error: err.Error()}
+}
+
+// forUser stamps the recipient on deliveries made to the devices of userID.
+func forUser(deliveries []sharedModels.NotificationDelivery, userID int) []sharedModels.NotificationDelivery {
+	for i := range deliveries {
+		deliveries[i].UserID = &userID
+	}
+	return deliveries
+}
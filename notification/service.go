@@ -0,0 +1,343 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Notification status
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusPartial = "partial"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // Stored for the inbox, but the recipient has no active device
+)
+
+// Delivery status
+const (
+	DeliverySent         = "sent"
+	DeliveryFailed       = "failed"
+	DeliveryInvalidToken = "invalid_token"
+)
+
+// Delivery channels
+const (
+	ChannelWebSocket = "websocket"
+	ChannelFCM       = "fcm"
+)
+
+// FCM limits
+const (
+	maxMulticastTokens = 500
+	maxTopicTokens     = 1000
+)
+
+// TopicAll is the topic SendToAll publishes to. Every device should be subscribed to it on registration.
+var TopicAll = "all"
+
+// TokenPruner removes registration tokens FCM reported as invalid from wherever they are stored.
+type TokenPruner interface {
+	PruneTokens(ctx context.Context, tokens []string) error
+}
+
+// Service persists notifications and pushes them through FCM.
+type Service struct {
+	DB     *gorm.DB
+	Client Client
+	Pruner TokenPruner // Optional
+
+	// Devices resolves the tokens of a user for SendToUser; set by NewDeviceRegistry
+	Devices *DeviceRegistry
+}
+
+// NewService returns a Service storing notifications in db and sending them with client.
+func NewService(db *gorm.DB, client Client) *Service {
+	return &Service{DB: db, Client: client}
+}
+
+// Message is the content of a notification.
+type Message struct {
+	UserID   *int   // Recipient, nil for broadcasts
+	UserType string // Defaults to "system"
+	Title    string
+	Body     string
+	Data     map[string]string
+}
+
+// SendToDevice stores and sends msg to a single registration token.
+func (s *Service) SendToDevice(ctx context.Context, msg Message, token string) (*sharedModels.Notification, error) {
+	n := newNotification(msg)
+	n.Token = &token
+	return s.deliver(ctx, n, func() []sharedModels.NotificationDelivery {
+		return s.sendTokens(ctx, n, []string{token})
+	})
+}
+
+// SendToDevices stores msg once and multicasts it to tokens in batches of 500.
+func (s *Service) SendToDevices(ctx context.Context, msg Message, tokens []string) (*sharedModels.Notification, error) {
+	tokens = unique(tokens)
+	if len(tokens) == 0 {
+		return nil, errors.New("no registration tokens")
+	}
+
+	n := newNotification(msg)
+	return s.deliver(ctx, n, func() []sharedModels.NotificationDelivery {
+		return s.sendTokens(ctx, n, tokens)
+	})
+}
+
+// SendToUser stores msg for userID and multicasts it to all of the user's active devices.
+func (s *Service) SendToUser(ctx context.Context, msg Message, userID int) (*sharedModels.Notification, error) {
+	if s.Devices == nil {
+		return nil, errors.New("no device registry configured")
+	}
+
+	tokens, err := s.Devices.ActiveTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load devices of user %d: %w", userID, err)
+	}
+
+	msg.UserID = &userID
+	n := newNotification(msg)
+	return s.deliver(ctx, n, func() []sharedModels.NotificationDelivery {
+		return forUser(s.sendTokens(ctx, n, tokens), userID)
+	})
+}
+
+// SendToTopic stores and publishes msg to the subscribers of topic.
+func (s *Service) SendToTopic(ctx context.Context, msg Message, topic string) (*sharedModels.Notification, error) {
+	n := newNotification(msg)
+	n.Topic = &topic
+	return s.deliver(ctx, n, func() []sharedModels.NotificationDelivery {
+		return s.sendTopic(ctx, n, topic)
+	})
+}
+
+// SendToAll stores msg as a broadcast and publishes it to TopicAll.
+func (s *Service) SendToAll(ctx context.Context, msg Message) (*sharedModels.Notification, error) {
+	msg.UserID = nil
+	n := newNotification(msg)
+	n.TargetAll = true
+	return s.deliver(ctx, n, func() []sharedModels.NotificationDelivery {
+		return s.sendTopic(ctx, n, TopicAll)
+	})
+}
+
+// Subscribe adds tokens to topic, pruning tokens FCM rejects as invalid.
+func (s *Service) Subscribe(ctx context.Context, tokens []string, topic string) error {
+	return s.manageTopic(ctx, tokens, topic, s.Client.SubscribeToTopic)
+}
+
+// Unsubscribe removes tokens from topic.
+func (s *Service) Unsubscribe(ctx context.Context, tokens []string, topic string) error {
+	return s.manageTopic(ctx, tokens, topic, s.Client.UnsubscribeFromTopic)
+}
+
+type topicFunc func(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+
+func (s *Service) manageTopic(ctx context.Context, tokens []string, topic string, fn topicFunc) error {
+	var invalid []string
+	for _, batch := range chunk(unique(tokens), maxTopicTokens) {
+		resp, err := fn(ctx, batch, topic)
+		if err != nil {
+			return fmt.Errorf("failed to update topic %s: %w", topic, err)
+		}
+		for _, info := range resp.Errors {
+			if info.Reason == "registration-token-not-registered" || info.Reason == ErrInvalidToken.Error() {
+				invalid = append(invalid, batch[info.Index])
+			}
+		}
+	}
+	return s.prune(ctx, invalid)
+}
+
+// deliver persists n, runs send and records its deliveries and the overall status.
+func (s *Service) deliver(ctx context.Context, n *sharedModels.Notification, send func() []sharedModels.NotificationDelivery) (*sharedModels.Notification, error) {
+	if err := s.save(ctx, n); err != nil {
+		return nil, err
+	}
+	return n, s.record(ctx, n, send())
+}
+
+// save persists n as pending.
+func (s *Service) save(ctx context.Context, n *sharedModels.Notification) error {
+	if err := s.DB.WithContext(ctx).Create(n).Error; err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	return nil
+}
+
+// record stores the deliveries of n and its overall status, and prunes the tokens FCM rejected.
+func (s *Service) record(ctx context.Context, n *sharedModels.Notification, deliveries []sharedModels.NotificationDelivery) error {
+	db := s.DB.WithContext(ctx)
+
+	var invalid []string
+	sent := 0
+	for i := range deliveries {
+		deliveries[i].NotificationID = n.ID
+		switch deliveries[i].Status {
+		case DeliverySent:
+			sent++
+		case DeliveryInvalidToken:
+			invalid = append(invalid, deliveries[i].Token)
+		}
+	}
+
+	switch {
+	case len(deliveries) == 0:
+		n.Status = StatusSkipped
+	case sent == len(deliveries):
+		n.Status = StatusSent
+	case sent == 0:
+		n.Status = StatusFailed
+	default:
+		n.Status = StatusPartial
+	}
+	if sent > 0 {
+		now := time.Now()
+		n.SentAt = &now
+	}
+
+	if len(deliveries) > 0 {
+		if err := db.CreateInBatches(&deliveries, 500).Error; err != nil {
+			return fmt.Errorf("failed to save notification deliveries: %w", err)
+		}
+	}
+	if err := db.Model(n).Select("Status", "SentAt").Updates(n).Error; err != nil {
+		return fmt.Errorf("failed to update notification status: %w", err)
+	}
+	n.Deliveries = deliveries
+
+	if err := s.prune(ctx, invalid); err != nil {
+		return err
+	}
+
+	if n.Status == StatusFailed {
+		return fmt.Errorf("notification %d was not delivered: %s", n.ID, deliveries[0].Error)
+	}
+	return nil
+}
+
+func (s *Service) sendTokens(ctx context.Context, n *sharedModels.Notification, tokens []string) []sharedModels.NotificationDelivery {
+	deliveries := make([]sharedModels.NotificationDelivery, 0, len(tokens))
+
+	for _, batch := range chunk(tokens, maxMulticastTokens) {
+		resp, err := s.Client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+			Tokens:       batch,
+			Data:         payload(n),
+			Notification: &messaging.Notification{Title: n.Title, Body: n.Body},
+		})
+		if err != nil {
+			for _, token := range batch {
+				deliveries = append(deliveries, newDelivery(token, "", "", err))
+			}
+			continue
+		}
+		for i, result := range resp.Responses {
+			deliveries = append(deliveries, newDelivery(batch[i], "", result.MessageID, result.Error))
+		}
+	}
+	return deliveries
+}
+
+func (s *Service) sendTopic(ctx context.Context, n *sharedModels.Notification, topic string) []sharedModels.NotificationDelivery {
+	id, err := s.Client.Send(ctx, &messaging.Message{
+		Topic:        topic,
+		Data:         payload(n),
+		Notification: &messaging.Notification{Title: n.Title, Body: n.Body},
+	})
+	return []sharedModels.NotificationDelivery{newDelivery("", topic, id, err)}
+}
+
+func (s *Service) prune(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 || s.Pruner == nil {
+		return nil
+	}
+	if err := s.Pruner.PruneTokens(ctx, tokens); err != nil {
+		return fmt.Errorf("failed to prune invalid tokens: %w", err)
+	}
+	return nil
+}
+
+func newNotification(msg Message) *sharedModels.Notification {
+	userType := msg.UserType
+	if userType == "" {
+		userType = "system"
+	}
+
+	n := &sharedModels.Notification{
+		UserID:   msg.UserID,
+		UserType: userType,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Status:   StatusPending,
+	}
+	if len(msg.Data) > 0 {
+		n.Data = datatypes.JSONMap{}
+		for key, value := range msg.Data {
+			n.Data[key] = value
+		}
+	}
+	return n
+}
+
+func newDelivery(token, topic, messageID string, err error) sharedModels.NotificationDelivery {
+	delivery := sharedModels.NotificationDelivery{
+		Channel:   ChannelFCM,
+		Token:     token,
+		Topic:     topic,
+		Status:    DeliverySent,
+		MessageID: messageID,
+	}
+	if err != nil {
+		delivery.Status = DeliveryFailed
+		if token != "" && IsInvalidToken(err) {
+			delivery.Status = DeliveryInvalidToken
+		}
+		delivery.Error = err.Error()
+	}
+	return delivery
+}
+
+// payload converts the stored data to the string map FCM expects, adding the notification ID
+// so clients can mark it read.
+func payload(n *sharedModels.Notification) map[string]string {
+	data := map[string]string{"notification_id": fmt.Sprint(n.ID)}
+	for key, value := range n.Data {
+		data[key] = fmt.Sprint(value)
+	}
+	return data
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		out = append(out, value)
+	}
+	return out
+}
+
+func chunk(values []string, size int) [][]string {
+	var chunks [][]string
+	for size < len(values) {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
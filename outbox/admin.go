@@ -0,0 +1,80 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DevdotSP/go-utils/queue"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/gorm"
+)
+
+// ErrNotRetryable is returned by Retry for an email that is not dead.
+var ErrNotRetryable = errors.New("email is not dead")
+
+// Page is one page of outbox emails, see queue.Page.
+type Page = queue.Page[sharedModels.EmailOutbox]
+
+// List returns the emails with status (all when empty) sent to recipient (all when empty), newest first.
+func (o *Outbox) List(ctx context.Context, status, recipient string, cursor int64, limit int) (*Page, error) {
+	query := o.DB.WithContext(ctx).Model(&sharedModels.EmailOutbox{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if recipient != "" {
+		query = query.Where("recipient = ?", strings.ToLower(recipient))
+	}
+
+	page, err := queue.List(query, cursor, limit, emailID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list emails: %w", err)
+	}
+	return page, nil
+}
+
+// Get returns an email with its delivery log.
+func (o *Outbox) Get(ctx context.Context, id int64) (*sharedModels.EmailOutbox, error) {
+	var row sharedModels.EmailOutbox
+	err := o.DB.WithContext(ctx).
+		Preload("Deliveries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&row, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// Retry queues a dead email again with a fresh set of MaxAttempts attempts, numbered after the previous ones.
+func (o *Outbox) Retry(ctx context.Context, id int64) error {
+	result := o.retry(o.DB.WithContext(ctx).Where("id = ?", id))
+	if result.Error != nil {
+		return fmt.Errorf("failed to retry email %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := o.Get(ctx, id); err != nil {
+			return err
+		}
+		return ErrNotRetryable
+	}
+	return nil
+}
+
+// RetryDead queues every dead email again and returns how many there were.
+func (o *Outbox) RetryDead(ctx context.Context) (int64, error) {
+	result := o.retry(o.DB.WithContext(ctx))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to retry emails: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (o *Outbox) retry(db *gorm.DB) *gorm.DB {
+	return db.Model(&sharedModels.EmailOutbox{}).Where("status = ?", StatusDead).Updates(map[string]interface{}{
+		"status":          StatusPending,
+		"max_attempts":    gorm.Expr("attempts + ?", max(o.MaxAttempts, 1)),
+		"next_attempt_at": time.Now(),
+	})
+}
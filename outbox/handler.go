@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// RegisterRoutes mounts the admin endpoints of the outbox on router, behind an admin-only middleware:
+// GET / lists emails (?status=&recipient=&cursor=&limit=), GET /:id returns one with its delivery log,
+// POST /retry retries every dead email and POST /:id/retry retries one.
+func (o *Outbox) RegisterRoutes(router fiber.Router) {
+	router.Get("/", o.ListHandler)
+	router.Post("/retry", o.RetryDeadHandler)
+	router.Get("/:id", o.GetHandler)
+	router.Post("/:id/retry", o.RetryHandler)
+}
+
+// ListHandler returns one page of emails.
+func (o *Outbox) ListHandler(c fiber.Ctx) error {
+	page, err := o.List(c.Context(), c.Query("status"), c.Query("recipient"), fiber.Query[int64](c, "cursor", 0), fiber.Query[int](c, "limit", 20))
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, page)
+}
+
+// GetHandler returns the email in :id with its delivery log.
+func (o *Outbox) GetHandler(c fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid email ID")
+	}
+
+	row, err := o.Get(c.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	}
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, row)
+}
+
+// RetryHandler queues the dead email in :id again.
+func (o *Outbox) RetryHandler(c fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid email ID")
+	}
+
+	err = o.Retry(c.Context(), id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, ErrNotRetryable):
+		return helper.JSONResponse(c, respcode.ERR_CODE_409, "Only dead emails can be retried")
+	case err != nil:
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponse(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG)
+}
+
+// RetryDeadHandler queues every dead email again and returns {"retried": n}.
+func (o *Outbox) RetryDeadHandler(c fiber.Ctx) error {
+	count, err := o.RetryDead(c.Context())
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, fiber.Map{"retried": count})
+}
@@ -0,0 +1,229 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DevdotSP/go-utils/mailer"
+	"github.com/DevdotSP/go-utils/queue"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/gorm"
+)
+
+// Outbox statuses
+const (
+	StatusPending = queue.StatusPending
+	StatusSending = queue.StatusSending
+	StatusSent    = "sent"
+	StatusDead    = queue.StatusDead // Only sent again through Retry
+)
+
+// Delivery statuses
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// RateLimit allows at most Count emails to one recipient per Per. Emails over the limit are delayed, not dropped.
+type RateLimit struct {
+	Count int
+	Per   time.Duration
+}
+
+// Outbox is an email queue. Emails are enqueued in the transaction of the change that triggers them,
+// so they are sent if and only if it commits, and the workers of its Pool send them.
+type Outbox struct {
+	DB     *gorm.DB
+	Mailer mailer.Mailer
+	queue.Pool
+
+	RateLimit RateLimit // Per recipient, disabled when Count is 0
+}
+
+// New returns an Outbox sending with m: 4 workers, 5 attempts from 30 seconds apart up to an hour,
+// and at most 10 emails per recipient per hour.
+func New(db *gorm.DB, m mailer.Mailer) *Outbox {
+	return &Outbox{
+		DB:     db,
+		Mailer: m,
+		Pool: queue.Pool{
+			Name:         "email",
+			Workers:      4,
+			BatchSize:    10,
+			PollInterval: 2 * time.Second,
+			Lease:        5 * time.Minute,
+			MaxAttempts:  5,
+			Backoff:      30 * time.Second,
+			MaxBackoff:   time.Hour,
+		},
+		RateLimit: RateLimit{Count: 10, Per: time.Hour},
+	}
+}
+
+// Enqueue queues msg in tx, the transaction of the business write it belongs to. The email gets a
+// Message-ID header kept across attempts, so receivers can drop duplicates of a resent email.
+func (o *Outbox) Enqueue(tx *gorm.DB, msg *mailer.Message) (*sharedModels.EmailOutbox, error) {
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return nil, mailer.ErrNoRecipients
+	}
+
+	messageID, err := newMessageID(msg.From)
+	if err != nil {
+		return nil, err
+	}
+	queued := *msg
+	queued.Headers = map[string]string{}
+	for key, value := range msg.Headers {
+		queued.Headers[key] = value
+	}
+	queued.Headers["Message-ID"] = messageID
+
+	data, err := json.Marshal(&queued)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	row := &sharedModels.EmailOutbox{
+		Recipient:     strings.ToLower(recipients[0]),
+		Subject:       msg.Subject,
+		Message:       data,
+		MessageID:     messageID,
+		Status:        StatusPending,
+		MaxAttempts:   o.MaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(row).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue email: %w", err)
+	}
+	return row, nil
+}
+
+// EnqueueTemplate renders email in locale with templates and queues it for to in tx.
+func (o *Outbox) EnqueueTemplate(tx *gorm.DB, templates *mailer.Templates, locale string, to []string, email mailer.Email) (*sharedModels.EmailOutbox, error) {
+	msg, err := templates.Render(locale, email)
+	if err != nil {
+		return nil, err
+	}
+	msg.To = to
+	return o.Enqueue(tx, msg)
+}
+
+// Start sends the queued emails until ctx is cancelled. Wait returns once the workers have stopped.
+func (o *Outbox) Start(ctx context.Context) {
+	o.Pool.Start(ctx, o.ProcessBatch)
+}
+
+// ProcessBatch sends up to BatchSize due emails, returning how many it claimed. It is exported to
+// drain the queue synchronously, e.g. in tests.
+func (o *Outbox) ProcessBatch(ctx context.Context) (int, error) {
+	return queue.Run(ctx, o.DB, &o.Pool, nil, emailID, o.process)
+}
+
+func emailID(row *sharedModels.EmailOutbox) int64 {
+	return row.ID
+}
+
+// process sends one claimed email and records the attempt.
+func (o *Outbox) process(ctx context.Context, row *sharedModels.EmailOutbox) error {
+	db := o.DB.WithContext(ctx)
+
+	if until, limited, err := o.rateLimited(ctx, row.Recipient); err != nil {
+		return err
+	} else if limited {
+		// Not an attempt: the email waits for the recipient's window to reopen
+		return o.Update(db, &sharedModels.EmailOutbox{}, row.ID, map[string]interface{}{"status": StatusPending, "next_attempt_at": until, "locked_until": nil})
+	}
+
+	var msg mailer.Message
+	sendErr := json.Unmarshal(row.Message, &msg)
+	permanent := sendErr != nil
+	started := time.Now()
+	if sendErr == nil {
+		sendErr = o.Mailer.Send(ctx, &msg)
+		permanent = mailer.Permanent(sendErr)
+	}
+	if sendErr != nil && ctx.Err() != nil {
+		// Shutting down: leave the email to the next worker once the lease expires
+		return nil
+	}
+
+	attempt := row.Attempts + 1
+	delivery := sharedModels.EmailDelivery{
+		OutboxID:  row.ID,
+		Attempt:   attempt,
+		Status:    DeliverySent,
+		MessageID: row.MessageID,
+		Duration:  time.Since(started).Milliseconds(),
+	}
+	updates := o.Attempt(attempt, row.MaxAttempts, sendErr, permanent, StatusSent)
+
+	switch {
+	case sendErr == nil:
+		updates["sent_at"] = time.Now()
+	case updates["status"] == StatusDead && permanent:
+		log.Printf("❌ Email %d to %s failed permanently: %v", row.ID, row.Recipient, sendErr)
+	case updates["status"] == StatusDead:
+		log.Printf("❌ Email %d to %s failed after %d attempts: %v", row.ID, row.Recipient, attempt, sendErr)
+	}
+	if sendErr != nil {
+		delivery.Status, delivery.Error = DeliveryFailed, sendErr.Error()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delivery).Error; err != nil {
+			return fmt.Errorf("failed to log email delivery: %w", err)
+		}
+		return o.Update(tx, &sharedModels.EmailOutbox{}, row.ID, updates)
+	})
+}
+
+// rateLimited reports whether recipient already received RateLimit.Count emails in the last RateLimit.Per,
+// and when the next one may be sent. The limit is approximate with concurrent workers.
+func (o *Outbox) rateLimited(ctx context.Context, recipient string) (time.Time, bool, error) {
+	if o.RateLimit.Count <= 0 || o.RateLimit.Per <= 0 {
+		return time.Time{}, false, nil
+	}
+
+	var sent []time.Time
+	err := o.DB.WithContext(ctx).Model(&sharedModels.EmailOutbox{}).
+		Where("recipient = ? AND status = ? AND sent_at > ?", recipient, StatusSent, time.Now().Add(-o.RateLimit.Per)).
+		Order("sent_at DESC").
+		Limit(o.RateLimit.Count).
+		Pluck("sent_at", &sent).Error
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(sent) < o.RateLimit.Count {
+		return time.Time{}, false, nil
+	}
+	// The window reopens when the oldest of the last Count emails leaves it
+	return sent[len(sent)-1].Add(o.RateLimit.Per), true, nil
+}
+
+// newMessageID returns a unique Message-ID in the domain of the sender.
+func newMessageID(from string) (string, error) {
+	if from == "" {
+		from = os.Getenv("MAIL_FROM_ADDRESS")
+	}
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">", nil
+}
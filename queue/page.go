@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"gorm.io/gorm"
+)
+
+// Page is one page of queue rows, newest first. Pass NextCursor back as the cursor to get the next page;
+// it is nil on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor *int64 `json:"next_cursor"`
+}
+
+// List returns the rows of query with an id below cursor (from the newest when 0), at most limit of
+// them, 20 when out of 1 to 100. id returns the id of a row.
+func List[T any](query *gorm.DB, cursor int64, limit int, id func(row *T) int64) (*Page[T], error) {
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	items := []T{}
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next := id(&page.Items[limit-1])
+		page.NextCursor = &next
+	}
+	return page, nil
+}
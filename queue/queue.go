@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Statuses shared by every queue, which adds its own for a row done with
+const (
+	StatusPending = "pending" // Waiting for its first or next attempt
+	StatusSending = "sending" // Claimed by a worker
+	StatusDead    = "dead"    // Out of attempts
+)
+
+// Pool runs the workers of a PostgreSQL-backed queue, such as the email outbox or the webhook dispatcher.
+// Its table has the id, status, attempts, max_attempts, next_attempt_at, locked_until and last_error
+// columns; workers on several instances share it.
+type Pool struct {
+	Name         string // Of a row in logs and errors, e.g. "email"
+	Workers      int
+	BatchSize    int           // Rows claimed by a worker at once
+	PollInterval time.Duration // Delay before polling an empty queue again
+	Lease        time.Duration // Rows claimed longer than this by a worker that died are processed again
+	MaxAttempts  int
+	Backoff      time.Duration // Delay after the first failure, doubled after every attempt
+	MaxBackoff   time.Duration
+
+	wg sync.WaitGroup
+}
+
+// Start runs the workers until ctx is cancelled, each calling batch in a loop and pausing for
+// PollInterval once it finds nothing to do. Wait returns once they have stopped.
+func (p *Pool) Start(ctx context.Context, batch func(ctx context.Context) (int, error)) {
+	workers := max(p.Workers, 1)
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work(ctx, batch)
+	}
+	log.Printf("✅ Started %d %s workers", workers, p.Name)
+}
+
+// Wait blocks until the workers started by Start have stopped.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) work(ctx context.Context, batch func(ctx context.Context) (int, error)) {
+	defer p.wg.Done()
+	for ctx.Err() == nil {
+		processed, err := batch(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ %s queue: %v", p.Name, err)
+		}
+		if processed > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.PollInterval):
+		}
+	}
+}
+
+// Run claims up to BatchSize due rows of T and calls process for each, returning how many were claimed.
+// scope narrows the rows that may be claimed and preloads their associations, it may be nil. A row
+// process fails is logged with its id and left to be claimed again once its lease expires, while the
+// rest of the batch goes on.
+func Run[T any](ctx context.Context, db *gorm.DB, p *Pool, scope func(*gorm.DB) *gorm.DB, id func(row *T) int64, process func(ctx context.Context, row *T) error) (int, error) {
+	rows, err := claim[T](ctx, db, p, scope)
+	if err != nil {
+		return 0, err
+	}
+	for i := range rows {
+		if err := process(ctx, &rows[i]); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Failed to process %s %d: %v", p.Name, id(&rows[i]), err)
+		}
+	}
+	return len(rows), nil
+}
+
+// claim marks due rows as sending under a lease: pending ones whose next attempt is due, and sending
+// ones whose lease expired. SKIP LOCKED lets workers claim different rows concurrently.
+func claim[T any](ctx context.Context, db *gorm.DB, p *Pool, scope func(*gorm.DB) *gorm.DB) ([]T, error) {
+	var rows []T
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?)", StatusPending, now, StatusSending, now)
+		if scope != nil {
+			query = scope(query)
+		}
+		err := query.Order("next_attempt_at").Limit(max(p.BatchSize, 1)).Find(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		// Updating the slice matches the rows by primary key
+		return tx.Model(&rows).Updates(map[string]interface{}{"status": StatusSending, "locked_until": now.Add(p.Lease)}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s rows: %w", p.Name, err)
+	}
+	return rows, nil
+}
+
+// Attempt returns the updates recording a claimed row's attempt, which failed with err unless nil:
+// the row becomes done on success, StatusDead once out of maxAttempts or when the failure is permanent,
+// and StatusPending until its next attempt, after the backoff, otherwise.
+func (p *Pool) Attempt(attempt, maxAttempts int, err error, permanent bool, done string) map[string]interface{} {
+	updates := map[string]interface{}{"attempts": attempt, "locked_until": nil}
+	switch {
+	case err == nil:
+		updates["status"] = done
+		updates["last_error"] = ""
+	case permanent, attempt >= max(maxAttempts, 1):
+		updates["status"] = StatusDead
+		updates["last_error"] = err.Error()
+	default:
+		updates["status"] = StatusPending
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = time.Now().Add(p.backoff(attempt))
+	}
+	return updates
+}
+
+// Update applies updates to the claimed row id of model, unless another worker claimed it since.
+func (p *Pool) Update(db *gorm.DB, model interface{}, id int64, updates map[string]interface{}) error {
+	err := db.Model(model).Where("id = ? AND status = ?", id, StatusSending).Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("failed to update %s %d: %w", p.Name, id, err)
+	}
+	return nil
+}
+
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxBackoff)
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/model"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ProtectedFields are the fields Store and Update never take from the request body, besides the primary key:
+// the audit and soft delete columns of sharedModels.BaseModel.
+var ProtectedFields = []string{"CreatedAt", "CreatedBy", "UpdatedAt", "UpdatedBy", "DeletedAt", "DeletedBy"}
+
+// CRUDController registers the standard list/show/store/update/delete routes for a Repository.
+// Embed it in a module controller and add custom handlers next to it.
+type CRUDController[T any, K ID] struct {
+	Repo       *Repository[T, K]
+	Filterable []string // Query parameters accepted as equality filters on Index
+	Preloads   []string // Associations preloaded on Index and Show
+	Protected  []string // Fields the request body cannot set besides the primary key, ProtectedFields when nil
+}
+
+// NewCRUDController returns a controller serving repo.
+func NewCRUDController[T any, K ID](repo *Repository[T, K]) *CRUDController[T, K] {
+	return &CRUDController[T, K]{Repo: repo}
+}
+
+// RegisterRoutes mounts the CRUD handlers on router.
+func (ctl *CRUDController[T, K]) RegisterRoutes(router fiber.Router) {
+	router.Get("/", ctl.Index)
+	router.Get("/:id", ctl.Show)
+	router.Post("/", ctl.Store)
+	router.Put("/:id", ctl.Update)
+	router.Delete("/:id", ctl.Delete)
+
+	if ctl.Repo.SoftDelete() {
+		router.Put("/:id/restore", ctl.Restore)
+		router.Delete("/:id/purge", ctl.Purge)
+	}
+}
+
+// Index lists records with ?page=&limit= (at most MaxLimit) and the Filterable query parameters.
+func (ctl *CRUDController[T, K]) Index(c fiber.Ctx) error {
+	page := fiber.Query[int](c, "page", 1)
+	limit := fiber.Query[int](c, "limit", 10)
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	filter := Filter{Where: map[string]interface{}{}, Preloads: ctl.Preloads}
+	for _, name := range ctl.Filterable {
+		if value := c.Query(name); value != "" {
+			filter.Where[name] = value
+		}
+	}
+
+	result, err := ctl.Repo.Paginate(c.Context(), page, limit, filter)
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+
+	return helper.JSONResponseWithDataPageDetails(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, result.Records, &model.PageDetails{
+		Page:       result.CurrentPage,
+		PageSize:   limit,
+		TotalItem:  int(result.TotalCount),
+		TotalPages: result.TotalPages,
+	})
+}
+
+// Show returns the record identified by :id.
+func (ctl *CRUDController[T, K]) Show(c fiber.Ctx) error {
+	id, err := ParseID[K](c.Params("id"))
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+	}
+
+	entity, err := ctl.Repo.FindByID(c.Context(), id, ctl.Preloads...)
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, entity)
+}
+
+// Store creates a record from the request body.
+func (ctl *CRUDController[T, K]) Store(c fiber.Ctx) error {
+	var entity T
+	if err := ctl.bind(c, &entity); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid input")
+	}
+
+	if err := ctl.Repo.Create(c.Context(), &entity); err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, entity)
+}
+
+// Update applies the request body to the record identified by :id.
+func (ctl *CRUDController[T, K]) Update(c fiber.Ctx) error {
+	id, err := ParseID[K](c.Params("id"))
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+	}
+
+	entity, err := ctl.Repo.FindByID(c.Context(), id)
+	if err != nil {
+		return respondError(c, err)
+	}
+
+	if err := ctl.bind(c, entity); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid input")
+	}
+
+	if err := ctl.Repo.Update(c.Context(), entity); err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, entity)
+}
+
+// Delete removes the record identified by :id.
+func (ctl *CRUDController[T, K]) Delete(c fiber.Ctx) error {
+	id, err := ParseID[K](c.Params("id"))
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+	}
+
+	if err := ctl.Repo.Delete(c.Context(), id); err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_DELETE, respcode.SUC_CODE_DELETE_MSG)
+}
+
+// Restore undoes the soft delete of the record identified by :id.
+func (ctl *CRUDController[T, K]) Restore(c fiber.Ctx) error {
+	id, err := ParseID[K](c.Params("id"))
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+	}
+
+	if err := ctl.Repo.Restore(c.Context(), id); err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_UPDATE, "Data restored successfully.")
+}
+
+// Purge permanently deletes the record identified by :id, bypassing soft delete.
+func (ctl *CRUDController[T, K]) Purge(c fiber.Ctx) error {
+	id, err := ParseID[K](c.Params("id"))
+	if err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid ID")
+	}
+
+	if err := ctl.Repo.ForceDelete(c.Context(), id); err != nil {
+		return respondError(c, err)
+	}
+
+	return helper.JSONResponse(c, respcode.SUC_CODE_DELETE, respcode.SUC_CODE_DELETE_MSG)
+}
+
+// bind applies the request body to entity, keeping its primary key and Protected fields as they are.
+func (ctl *CRUDController[T, K]) bind(c fiber.Ctx, entity *T) error {
+	ctx := c.Context()
+	rv := reflect.ValueOf(entity).Elem()
+
+	sch := ctl.Repo.Schema()
+	fields := append([]*schema.Field{}, sch.PrimaryFields...)
+	names := ctl.Protected
+	if names == nil {
+		names = ProtectedFields
+	}
+	for _, name := range names {
+		if field := sch.LookUpField(name); field != nil {
+			fields = append(fields, field)
+		}
+	}
+
+	// Cleared while binding, so the body cannot write through a pointer shared with the kept value
+	kept := make([]reflect.Value, len(fields))
+	for i, field := range fields {
+		value := field.ReflectValueOf(ctx, rv)
+		kept[i] = reflect.New(value.Type()).Elem()
+		kept[i].Set(value)
+		value.Set(reflect.Zero(value.Type()))
+	}
+
+	if err := c.Bind().Body(entity); err != nil {
+		return err
+	}
+	for i, field := range fields {
+		field.ReflectValueOf(ctx, rv).Set(kept[i])
+	}
+	return nil
+}
+
+// respondError maps repository errors to the respcode vocabulary.
+func respondError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, gorm.ErrDuplicatedKey), utils.IsUniqueConstraintError(err):
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_409, respcode.ERR_CODE_409_MSG, err)
+	default:
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+}
+
+// ParseID converts a path parameter to the primary key type K.
+func ParseID[K ID](raw string) (K, error) {
+	var id K
+	v := reflect.ValueOf(&id).Elem()
+
+	switch v.Kind() {
+	case reflect.String:
+		if raw == "" {
+			return id, fmt.Errorf("empty id")
+		}
+		v.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return id, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return id, err
+		}
+		v.SetUint(n)
+	}
+
+	return id, nil
+}
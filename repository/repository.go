@@ -0,0 +1,406 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ID lists the primary key types supported by Repository.
+type ID interface {
+	~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64 | ~string
+}
+
+// MaxLimit caps the page size of Paginate.
+const MaxLimit = 100
+
+var (
+	ErrNoPrimaryKey  = errors.New("model has no primary key")
+	ErrNotSoftDelete = errors.New("model does not support soft delete")
+)
+
+// Filter narrows FindAll, Count, Paginate and batch operations.
+type Filter struct {
+	Where    map[string]interface{}    // Equality conditions, column => value
+	Scopes   []func(*gorm.DB) *gorm.DB // Extra conditions (ranges, LIKE, joins)
+	Preloads []string                  // Associations to preload
+	Order    string                    // Defaults to the primary key descending
+	Limit    int                       // 0 means no limit (FindAll only)
+	Unscoped bool                      // Include soft-deleted rows
+}
+
+// Hooks are optional callbacks run around repository writes.
+// A Before hook returning an error aborts the operation.
+type Hooks[T any, K ID] struct {
+	BeforeCreate func(ctx context.Context, entity *T) error
+	AfterCreate  func(ctx context.Context, entity *T) error
+	BeforeUpdate func(ctx context.Context, entity *T) error
+	AfterUpdate  func(ctx context.Context, entity *T) error
+	BeforeDelete func(ctx context.Context, id K) error
+	AfterDelete  func(ctx context.Context, id K) error
+}
+
+// Repository provides context-aware CRUD for a GORM model T with primary key type K.
+type Repository[T any, K ID] struct {
+	db        *gorm.DB
+	schema    *schema.Schema
+	deletedAt *schema.Field // nil when T is hard-deleted
+	Hooks     Hooks[T, K]
+}
+
+// NewRepository parses the schema of T and returns a repository bound to db.
+func NewRepository[T any, K ID](db *gorm.DB) (*Repository[T, K], error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %w", err)
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, ErrNoPrimaryKey
+	}
+
+	return &Repository[T, K]{db: db, schema: stmt.Schema, deletedAt: softDeleteField(stmt.Schema)}, nil
+}
+
+// softDeleteField finds the gorm.DeletedAt (or compatible) field of a schema.
+func softDeleteField(sch *schema.Schema) *schema.Field {
+	for _, field := range sch.Fields {
+		if _, ok := reflect.New(field.FieldType).Interface().(schema.DeleteClausesInterface); ok {
+			return field
+		}
+	}
+	return nil
+}
+
+// MustNewRepository is NewRepository for route registration, panicking on an invalid model.
+func MustNewRepository[T any, K ID](db *gorm.DB) *Repository[T, K] {
+	r, err := NewRepository[T, K](db)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// WithTx returns a copy of the repository that runs on tx.
+func (r *Repository[T, K]) WithTx(tx *gorm.DB) *Repository[T, K] {
+	return &Repository[T, K]{db: tx, schema: r.schema, deletedAt: r.deletedAt, Hooks: r.Hooks}
+}
+
+// Transaction runs fn with a repository bound to a new transaction.
+func (r *Repository[T, K]) Transaction(ctx context.Context, fn func(tx *Repository[T, K]) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(r.WithTx(tx))
+	})
+}
+
+// DB returns the underlying connection.
+func (r *Repository[T, K]) DB() *gorm.DB {
+	return r.db
+}
+
+// Schema returns the parsed GORM schema of T.
+func (r *Repository[T, K]) Schema() *schema.Schema {
+	return r.schema
+}
+
+// SoftDelete reports whether T has a gorm.DeletedAt field, in which case Delete only marks rows.
+func (r *Repository[T, K]) SoftDelete() bool {
+	return r.deletedAt != nil
+}
+
+func (r *Repository[T, K]) primaryKey() string {
+	return r.schema.PrioritizedPrimaryField.DBName
+}
+
+func (r *Repository[T, K]) query(ctx context.Context, filter Filter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(new(T))
+	if filter.Unscoped {
+		query = query.Unscoped()
+	}
+	if len(filter.Where) > 0 {
+		query = query.Where(filter.Where)
+	}
+	if len(filter.Scopes) > 0 {
+		query = query.Scopes(filter.Scopes...)
+	}
+	return query
+}
+
+// Create inserts entity.
+func (r *Repository[T, K]) Create(ctx context.Context, entity *T) error {
+	if r.Hooks.BeforeCreate != nil {
+		if err := r.Hooks.BeforeCreate(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return err
+	}
+
+	if r.Hooks.AfterCreate != nil {
+		return r.Hooks.AfterCreate(ctx, entity)
+	}
+	return nil
+}
+
+// CreateBatch inserts entities in batches of batchSize, running the create hooks for each entity.
+func (r *Repository[T, K]) CreateBatch(ctx context.Context, entities []T, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	return r.Transaction(ctx, func(tx *Repository[T, K]) error {
+		if tx.Hooks.BeforeCreate != nil {
+			for i := range entities {
+				if err := tx.Hooks.BeforeCreate(ctx, &entities[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.db.WithContext(ctx).CreateInBatches(&entities, batchSize).Error; err != nil {
+			return err
+		}
+
+		if tx.Hooks.AfterCreate != nil {
+			for i := range entities {
+				if err := tx.Hooks.AfterCreate(ctx, &entities[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// FindByID returns the entity with the given primary key or gorm.ErrRecordNotFound.
+func (r *Repository[T, K]) FindByID(ctx context.Context, id K, preloads ...string) (*T, error) {
+	return r.FindOne(ctx, Filter{Where: map[string]interface{}{r.primaryKey(): id}, Preloads: preloads})
+}
+
+// FindOne returns the first entity matching filter or gorm.ErrRecordNotFound.
+func (r *Repository[T, K]) FindOne(ctx context.Context, filter Filter) (*T, error) {
+	query := r.query(ctx, filter)
+	for _, preload := range filter.Preloads {
+		query = query.Preload(preload)
+	}
+
+	var entity T
+	if err := query.First(&entity).Error; err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// FindAll returns every entity matching filter.
+func (r *Repository[T, K]) FindAll(ctx context.Context, filter Filter) ([]T, error) {
+	query := r.query(ctx, filter)
+	for _, preload := range filter.Preloads {
+		query = query.Preload(preload)
+	}
+
+	order := filter.Order
+	if order == "" {
+		order = r.primaryKey() + " DESC"
+	}
+	query = query.Order(order)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entities []T
+	if err := query.Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// Paginate returns one page of entities matching filter using utils.Paginate. limit is capped at MaxLimit.
+func (r *Repository[T, K]) Paginate(ctx context.Context, page, limit int, filter Filter) (*utils.PaginatedResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	db := r.db.WithContext(ctx)
+	if filter.Unscoped {
+		db = db.Unscoped()
+	}
+	if len(filter.Scopes) > 0 {
+		db = db.Scopes(filter.Scopes...)
+	}
+	order := filter.Order
+	if order == "" {
+		order = r.primaryKey() + " DESC"
+	}
+
+	entities := []T{}
+	return utils.Paginate(db.Order(order), &entities, page, limit, filter.Where, filter.Preloads)
+}
+
+// Count returns the number of entities matching filter.
+func (r *Repository[T, K]) Count(ctx context.Context, filter Filter) (int64, error) {
+	var count int64
+	err := r.query(ctx, filter).Count(&count).Error
+	return count, err
+}
+
+// Exists reports whether an entity with the given primary key exists.
+func (r *Repository[T, K]) Exists(ctx context.Context, id K) (bool, error) {
+	count, err := r.Count(ctx, Filter{Where: map[string]interface{}{r.primaryKey(): id}})
+	return count > 0, err
+}
+
+// Update saves every field of entity.
+func (r *Repository[T, K]) Update(ctx context.Context, entity *T) error {
+	if r.Hooks.BeforeUpdate != nil {
+		if err := r.Hooks.BeforeUpdate(ctx, entity); err != nil {
+			return err
+		}
+	}
+
+	if err := r.db.WithContext(ctx).Save(entity).Error; err != nil {
+		return err
+	}
+
+	if r.Hooks.AfterUpdate != nil {
+		return r.Hooks.AfterUpdate(ctx, entity)
+	}
+	return nil
+}
+
+// UpdateFields updates the given columns of the entity with the given primary key.
+func (r *Repository[T, K]) UpdateFields(ctx context.Context, id K, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(new(T)).Where(r.primaryKey()+" = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateWhere updates the given columns of every entity matching filter and returns the number of rows changed.
+func (r *Repository[T, K]) UpdateWhere(ctx context.Context, filter Filter, updates map[string]interface{}) (int64, error) {
+	if len(filter.Where) == 0 && len(filter.Scopes) == 0 {
+		return 0, gorm.ErrMissingWhereClause
+	}
+	result := r.query(ctx, filter).Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+// Delete removes the entity with the given primary key. Models with gorm.DeletedAt are soft-deleted.
+func (r *Repository[T, K]) Delete(ctx context.Context, id K) error {
+	return r.delete(ctx, id, false)
+}
+
+// ForceDelete permanently removes the entity, bypassing soft delete.
+func (r *Repository[T, K]) ForceDelete(ctx context.Context, id K) error {
+	return r.delete(ctx, id, true)
+}
+
+func (r *Repository[T, K]) delete(ctx context.Context, id K, force bool) error {
+	if r.Hooks.BeforeDelete != nil {
+		if err := r.Hooks.BeforeDelete(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	db := r.db.WithContext(ctx)
+	if force {
+		db = db.Unscoped()
+	}
+	result := db.Where(r.primaryKey()+" = ?", id).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	if r.Hooks.AfterDelete != nil {
+		return r.Hooks.AfterDelete(ctx, id)
+	}
+	return nil
+}
+
+// DeleteBatch removes every entity whose primary key is in ids and returns the number of rows deleted.
+func (r *Repository[T, K]) DeleteBatch(ctx context.Context, ids []K) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := r.Transaction(ctx, func(tx *Repository[T, K]) error {
+		if tx.Hooks.BeforeDelete != nil {
+			for _, id := range ids {
+				if err := tx.Hooks.BeforeDelete(ctx, id); err != nil {
+					return err
+				}
+			}
+		}
+
+		result := tx.db.WithContext(ctx).Where(tx.primaryKey()+" IN ?", ids).Delete(new(T))
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		if tx.Hooks.AfterDelete != nil {
+			for _, id := range ids {
+				if err := tx.Hooks.AfterDelete(ctx, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// Restore clears the soft delete of the entity with the given primary key.
+func (r *Repository[T, K]) Restore(ctx context.Context, id K) error {
+	if r.deletedAt == nil {
+		return ErrNotSoftDelete
+	}
+
+	updates := map[string]interface{}{r.deletedAt.DBName: nil}
+	if field := r.schema.LookUpField("DeletedBy"); field != nil {
+		updates[field.DBName] = ""
+	}
+
+	result := r.db.WithContext(ctx).Unscoped().Model(new(T)).
+		Where(r.primaryKey()+" = ? AND "+r.deletedAt.DBName+" IS NOT NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes soft-deleted entities deleted before the given time and returns the number removed.
+func (r *Repository[T, K]) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if r.deletedAt == nil {
+		return 0, ErrNotSoftDelete
+	}
+
+	result := r.db.WithContext(ctx).Unscoped().
+		Where(r.deletedAt.DBName+" IS NOT NULL AND "+r.deletedAt.DBName+" < ?", before).
+		Delete(new(T))
+	return result.RowsAffected, result.Error
+}
@@ -47,8 +47,14 @@ const (
 	ERR_CODE_404           = "404"
 	ERR_CODE_404_MSG       = "Resource not found."
 
+	ERR_CODE_405           = "405"
+	ERR_CODE_405_MSG       = "Method not allowed."
+
 	ERR_CODE_409           = "409"
 	ERR_CODE_409_MSG       = "Conflict. Duplicate or already exists."
+
+	ERR_CODE_413           = "413"
+	ERR_CODE_413_MSG       = "Payload too large."
 )
 
 // ❗ Server Error Codes
@@ -1,7 +1,5 @@
 package sharedModels
 
-import "time"
-
 
 
 // Address Model.
@@ -21,7 +19,7 @@ type Address struct {
 	Municipality     Municipality `gorm:"foreignKey:MunicipalityCode;references:Code"`
 	BarangayCode     string       `json:"barangay_code"`
 	Barangay         Barangay     `gorm:"foreignKey:BarangayCode;references:Code"`
-	CreatedAt        time.Time    `json:"created_at"`
+	BaseModel
 }
 
 func (Address) TableName() string {
@@ -1,20 +1,21 @@
 package sharedModels
 
-import "time"
+import "gorm.io/datatypes"
 
 type Advertisement struct {
-	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name        string    `gorm:"type:text;not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	Title       string    `gorm:"type:text;not null" json:"title"`
-	URLImage    string    `gorm:"type:text;not null" json:"url_image"` // single image path
-	CreatedAt   time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
-	CreatedBy   string    `gorm:"type:varchar(100);not null" json:"created_by"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
-	UpdatedBy   string    `gorm:"type:varchar(100)" json:"updated_by"`
+	ID            int               `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name          string            `gorm:"type:text;not null" json:"name"`
+	Description   string            `gorm:"type:text" json:"description"`
+	Title         string            `gorm:"type:text;not null" json:"title"`
+	URLImage      string            `gorm:"type:text;not null" json:"url_image"`          // single image path
+	ImageVariants datatypes.JSONMap `gorm:"type:jsonb" json:"image_variants,omitempty"`   // Variant name => URL
+	ImageKey      string            `gorm:"type:text" json:"-"`                           // Storage prefix of the image and its variants
+	CreatedBy     string            `gorm:"type:varchar(100);not null" json:"created_by"` // Override the BaseModel columns, keeping their constraints
+	UpdatedBy     string            `gorm:"type:varchar(100)" json:"updated_by"`
+	BaseModel
 }
 
 // TableName sets the table name for Advertisement
 func (Advertisement) TableName() string {
 	return "v1.advertisements"
-}
\ No newline at end of file
+}
@@ -0,0 +1,28 @@
+package sharedModels
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// AuditLog records one create, update or delete of an audited model.
+// @swagger:model
+type AuditLog struct {
+	ID            int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	EntityType    string         `gorm:"type:varchar(100);not null;index:idx_audit_log_entity,priority:1" json:"entity_type"` // Go model name, e.g. WebUser
+	EntityID      string         `gorm:"type:varchar(100);not null;index:idx_audit_log_entity,priority:2" json:"entity_id"`
+	Action        string         `gorm:"type:varchar(20);not null" json:"action"` // "create", "update" or "delete"
+	Before        datatypes.JSON `gorm:"type:jsonb" json:"before,omitempty"`
+	After         datatypes.JSON `gorm:"type:jsonb" json:"after,omitempty"`
+	ChangedFields datatypes.JSON `gorm:"type:jsonb" json:"changed_fields,omitempty"`
+	Actor         string         `gorm:"type:varchar(100);index" json:"actor"`
+	RequestID     string         `gorm:"type:varchar(64)" json:"request_id"`
+	IP            string         `gorm:"type:varchar(45)" json:"ip"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime;type:timestamptz;index" json:"created_at"`
+}
+
+// TableName overrides the default table name
+func (AuditLog) TableName() string {
+	return "v1.audit_log"
+}
@@ -0,0 +1,179 @@
+package sharedModels
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// BaseModel adds audit columns and soft delete to a model.
+// CreatedBy, UpdatedBy and DeletedBy are filled from utils.ActorFromContext by the callbacks
+// registered with RegisterCallbacks, so queries must run with db.WithContext(c.Context()).
+type BaseModel struct {
+	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+	UpdatedBy string    `json:"updated_by"`
+	DeletedAt DeletedAt `gorm:"index;type:timestamptz" json:"deleted_at,omitempty"`
+	DeletedBy string    `json:"deleted_by,omitempty"`
+}
+
+// IsDeleted reports whether the record has been soft-deleted.
+func (m BaseModel) IsDeleted() bool {
+	return m.DeletedAt.Valid
+}
+
+// DeletedAt behaves like gorm.DeletedAt and also records DeletedBy when a row is soft-deleted.
+type DeletedAt sql.NullTime
+
+// Scan implements the Scanner interface.
+func (n *DeletedAt) Scan(value interface{}) error {
+	return (*sql.NullTime)(n).Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n DeletedAt) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+func (n DeletedAt) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return json.Marshal(n.Time)
+	}
+	return json.Marshal(nil)
+}
+
+func (n *DeletedAt) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		n.Valid = false
+		return nil
+	}
+	err := json.Unmarshal(b, &n.Time)
+	if err == nil {
+		n.Valid = true
+	}
+	return err
+}
+
+func (DeletedAt) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteQueryClause{Field: f}}
+}
+
+func (DeletedAt) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteUpdateClause{Field: f}}
+}
+
+func (DeletedAt) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{softDeleteClause{Field: f}}
+}
+
+// softDeleteClause is gorm.SoftDeleteDeleteClause with the deleted_by column added to the SET list.
+type softDeleteClause struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteClause) Name() string {
+	return ""
+}
+
+func (sd softDeleteClause) Build(clause.Builder) {
+}
+
+func (sd softDeleteClause) MergeClause(*clause.Clause) {
+}
+
+func (sd softDeleteClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() != 0 || stmt.Statement.Unscoped {
+		return
+	}
+
+	curTime := stmt.DB.NowFunc()
+	set := clause.Set{{Column: clause.Column{Name: sd.Field.DBName}, Value: curTime}}
+	stmt.SetColumn(sd.Field.DBName, curTime, true)
+
+	if actor := utils.ActorFromContext(stmt.Context); actor != "" && stmt.Schema != nil {
+		if field := stmt.Schema.LookUpField("DeletedBy"); field != nil {
+			set = append(set, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: actor})
+			stmt.SetColumn(field.DBName, actor, true)
+		}
+	}
+	stmt.AddClause(set)
+
+	if stmt.Schema != nil {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+
+		if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+			_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+			column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+
+			if len(values) > 0 {
+				stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+			}
+		}
+	}
+
+	gorm.SoftDeleteQueryClause{Field: sd.Field}.ModifyStatement(stmt)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(stmt.DB.Callback().Update().Clauses...)
+}
+
+// RegisterCallbacks installs the GORM callbacks that stamp CreatedBy and UpdatedBy from the request context.
+func RegisterCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("shared_models:stamp_create", stampCreate); err != nil {
+		return err
+	}
+	return db.Callback().Update().Before("gorm:update").Register("shared_models:stamp_update", stampUpdate)
+}
+
+func stampCreate(db *gorm.DB) {
+	actor := utils.ActorFromContext(db.Statement.Context)
+	if actor == "" || db.Statement.Schema == nil {
+		return
+	}
+
+	for _, name := range []string{"CreatedBy", "UpdatedBy"} {
+		field := db.Statement.Schema.LookUpField(name)
+		if field == nil {
+			continue
+		}
+
+		switch db.Statement.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+				stampIfZero(db, field, db.Statement.ReflectValue.Index(i), actor)
+			}
+		case reflect.Struct:
+			stampIfZero(db, field, db.Statement.ReflectValue, actor)
+		}
+	}
+}
+
+// stampIfZero keeps an explicitly provided value, e.g. a system job setting CreatedBy itself.
+func stampIfZero(db *gorm.DB, field *schema.Field, rv reflect.Value, actor string) {
+	if _, isZero := field.ValueOf(db.Statement.Context, rv); isZero {
+		db.AddError(field.Set(db.Statement.Context, rv, actor))
+	}
+}
+
+func stampUpdate(db *gorm.DB) {
+	actor := utils.ActorFromContext(db.Statement.Context)
+	if actor == "" || db.Statement.Schema == nil || db.Statement.Schema.LookUpField("UpdatedBy") == nil {
+		return
+	}
+	db.Statement.SetColumn("UpdatedBy", actor, true)
+}
@@ -0,0 +1,50 @@
+package sharedModels
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// EmailOutbox is an email queued for delivery. Rows are written in the transaction of the business change
+// that triggers them and stay in the table once sent, as the delivery log of the message.
+type EmailOutbox struct {
+	ID            int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Recipient     string         `gorm:"type:varchar(320);not null;index:idx_email_outbox_recipient_sent" json:"recipient"` // First recipient, used for rate limiting
+	Subject       string         `gorm:"not null" json:"subject"`
+	Message       datatypes.JSON `gorm:"type:jsonb;not null" json:"-"`                                  // Encoded mailer.Message
+	MessageID     string         `gorm:"type:varchar(255);uniqueIndex" json:"message_id"`               // Message-ID header, the same on every attempt
+	Status        string         `gorm:"type:varchar(20);not null;default:pending;index" json:"status"` // "pending", "sending", "sent" or "dead"
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts   int            `gorm:"not null;default:5" json:"max_attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	NextAttemptAt time.Time      `gorm:"type:timestamptz;not null;index" json:"next_attempt_at"`
+	LockedUntil   *time.Time     `gorm:"type:timestamptz" json:"-"` // Lease of the worker sending it
+	SentAt        *time.Time     `gorm:"type:timestamptz;index:idx_email_outbox_recipient_sent" json:"sent_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+
+	Deliveries []EmailDelivery `json:"deliveries,omitempty" gorm:"foreignKey:OutboxID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// TableName explicitly sets the table name
+func (EmailOutbox) TableName() string {
+	return "v1.email_outbox"
+}
+
+// EmailDelivery records one attempt to send an EmailOutbox message.
+type EmailDelivery struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	OutboxID  int64     `gorm:"not null;index" json:"outbox_id"`
+	Attempt   int       `gorm:"not null" json:"attempt"`
+	Status    string    `gorm:"type:varchar(20);not null" json:"status"` // "sent" or "failed"
+	MessageID string    `gorm:"type:varchar(255)" json:"message_id"`
+	Error     string    `json:"error,omitempty"`
+	Duration  int64     `json:"duration_ms"`
+	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+}
+
+// TableName explicitly sets the table name
+func (EmailDelivery) TableName() string {
+	return "v1.email_delivery"
+}
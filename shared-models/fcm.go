@@ -2,19 +2,28 @@ package sharedModels
 
 import (
 	"time"
+
+	"gorm.io/datatypes"
 )
 
 type Notification struct {
-	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID    *int      `json:"user_id" gorm:"index;default:null"` // Nullable for broadcasts
-	UserType  string    `json:"user_type" gorm:"not null"`         // "customer", "agent", "officer", or "system"
-	Title     string    `json:"title" gorm:"not null"`
-	Body      string    `json:"body" gorm:"not null"`
-	Token     *string   `json:"token" gorm:"default:null"`         // Nullable for topic notifications
-	Topic     *string   `json:"topic" gorm:"default:null"`         // New field for topic-based notifications
-	IsRead    bool      `json:"is_read" gorm:"default:false"`
-	TargetAll bool      `json:"target_all" gorm:"default:false"`   // If true, broadcast to all
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID         int               `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     *int              `json:"user_id" gorm:"index;default:null"` // Nullable for broadcasts
+	UserType   string            `json:"user_type" gorm:"not null"`         // "customer", "agent", "officer", or "system"
+	Title      string            `json:"title" gorm:"not null"`
+	Body       string            `json:"body" gorm:"not null"`
+	Token      *string           `json:"token" gorm:"default:null"` // Nullable for topic notifications
+	Topic      *string           `json:"topic" gorm:"default:null"` // New field for topic-based notifications
+	IsRead     bool              `json:"is_read" gorm:"default:false"`
+	TargetAll  bool              `json:"target_all" gorm:"default:false"`                // If true, broadcast to all
+	Data       datatypes.JSONMap `json:"data,omitempty" gorm:"type:jsonb"`               // Key/value payload delivered with the push
+	Status     string            `json:"status" gorm:"type:varchar(20);default:pending"` // "pending", "sent", "partial", "failed" or "skipped"
+	SentAt     *time.Time        `json:"sent_at,omitempty"`
+	ArchivedAt *time.Time        `json:"archived_at,omitempty"` // Inbox state of direct notifications; broadcasts use NotificationReceipt
+	CreatedAt  time.Time         `gorm:"autoCreateTime" json:"created_at"`
+
+	Deliveries []NotificationDelivery `json:"deliveries,omitempty" gorm:"foreignKey:NotificationID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Receipts   []NotificationReceipt  `json:"-" gorm:"foreignKey:NotificationID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
 // TableName explicitly sets the table name
@@ -22,6 +31,60 @@ func (Notification) TableName() string {
 	return "v1.notification"
 }
 
+// NotificationDelivery records the result of a notification for one recipient: a user reached over WebSocket,
+// or a device token or topic reached through FCM.
+type NotificationDelivery struct {
+	ID             int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	NotificationID int       `gorm:"not null;index" json:"notification_id"`
+	UserID         *int      `gorm:"index" json:"user_id,omitempty"`                       // Recipient, when known
+	Channel        string    `gorm:"type:varchar(20);not null;default:fcm" json:"channel"` // "websocket" or "fcm"
+	Token          string    `gorm:"index" json:"token,omitempty"`
+	Topic          string    `json:"topic,omitempty"`
+	Status         string    `gorm:"type:varchar(20);not null" json:"status"` // "sent", "failed" or "invalid_token"
+	MessageID      string    `json:"message_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+}
+
+// TableName explicitly sets the table name
+func (NotificationDelivery) TableName() string {
+	return "v1.notification_delivery"
+}
+
+// NotificationReceipt holds the inbox state of a broadcast for one user, since a broadcast row is
+// shared by every recipient. A deleted broadcast stays in the table with DeletedAt set so it is not shown again.
+type NotificationReceipt struct {
+	NotificationID int        `gorm:"primaryKey;autoIncrement:false" json:"notification_id"`
+	UserID         int        `gorm:"primaryKey;autoIncrement:false;index" json:"user_id"`
+	ReadAt         *time.Time `gorm:"type:timestamptz" json:"read_at,omitempty"`
+	ArchivedAt     *time.Time `gorm:"type:timestamptz" json:"archived_at,omitempty"`
+	DeletedAt      *time.Time `gorm:"type:timestamptz" json:"deleted_at,omitempty"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+}
+
+// TableName explicitly sets the table name
+func (NotificationReceipt) TableName() string {
+	return "v1.notification_receipt"
+}
+
+// UserDevice is a device registered for push notifications. A token belongs to one user at a time:
+// registering it again under another user moves it.
+type UserDevice struct {
+	ID         int                         `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int                         `gorm:"not null;index" json:"user_id"`
+	Token      string                      `gorm:"not null;uniqueIndex" json:"token"`
+	Platform   string                      `gorm:"type:varchar(20)" json:"platform"` // "android", "ios" or "web"
+	AppVersion string                      `gorm:"type:varchar(50)" json:"app_version"`
+	Topics     datatypes.JSONSlice[string] `gorm:"type:jsonb" json:"topics"` // Topics the token is subscribed to
+	LastSeenAt time.Time                   `gorm:"type:timestamptz;index" json:"last_seen_at"`
+	CreatedAt  time.Time                   `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	UpdatedAt  time.Time                   `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+}
+
+// TableName explicitly sets the table name
+func (UserDevice) TableName() string {
+	return "v1.user_device"
+}
 
 type SubscriptionRequest struct {
 	Token string `json:"token" validate:"required"`
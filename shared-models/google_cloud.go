@@ -2,16 +2,20 @@ package sharedModels
 
 import (
 	"time"
+
+	"gorm.io/datatypes"
 )
 
 // UserImage represents an uploaded image associated with a user.
 type UserImage struct {
-	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID    int       `json:"user_id" gorm:"index"` // Index for faster queries
-	ImageType string    `json:"image_type" gorm:"not null"`
-	ImageURL  string    `json:"image_url" gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz;default:now()" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime;type:timestamptz;default:now()" json:"updated_at"`
+	ID        int               `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int               `json:"user_id" gorm:"index"` // Index for faster queries
+	ImageType string            `json:"image_type" gorm:"not null"`
+	ImageURL  string            `json:"image_url" gorm:"not null"`
+	Variants  datatypes.JSONMap `json:"variants,omitempty" gorm:"type:jsonb"` // Variant name => URL, e.g. "thumb"
+	ImageKey  string            `json:"-" gorm:"type:text"`                   // Storage prefix of the image and its variants
+	CreatedAt time.Time         `gorm:"autoCreateTime;type:timestamptz;default:now()" json:"created_at"`
+	UpdatedAt time.Time         `gorm:"autoUpdateTime;type:timestamptz;default:now()" json:"updated_at"`
 }
 
 // TableName explicitly sets the table name for UserImage.
@@ -0,0 +1,38 @@
+package sharedModels
+
+import "time"
+
+// SystemParameter is a runtime setting read through the sysparam service. Value is stored as text and
+// parsed according to Type.
+type SystemParameter struct {
+	Key         string    `gorm:"primaryKey;type:varchar(150)" json:"key"` // Dotted name, e.g. "storage.gcs_path"
+	Value       string    `gorm:"type:text;not null" json:"value"`
+	Type        string    `gorm:"type:varchar(20);not null;default:string" json:"type"` // "string", "int", "bool", "duration" or "json"
+	Description string    `json:"description,omitempty"`
+	UpdatedBy   string    `gorm:"type:varchar(100)" json:"updated_by,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+}
+
+// TableName explicitly sets the table name
+func (SystemParameter) TableName() string {
+	return "v1.system_parameter"
+}
+
+// SystemParameterHistory records one change of a SystemParameter.
+type SystemParameterHistory struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key       string    `gorm:"type:varchar(150);not null;index" json:"key"`
+	Action    string    `gorm:"type:varchar(20);not null" json:"action"` // "create", "update" or "delete"
+	OldValue  *string   `gorm:"type:text" json:"old_value"`
+	NewValue  *string   `gorm:"type:text" json:"new_value"`
+	OldType   string    `gorm:"type:varchar(20)" json:"old_type,omitempty"`
+	NewType   string    `gorm:"type:varchar(20)" json:"new_type,omitempty"`
+	ChangedBy string    `gorm:"type:varchar(100);index" json:"changed_by"`
+	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+}
+
+// TableName explicitly sets the table name
+func (SystemParameterHistory) TableName() string {
+	return "v1.system_parameter_history"
+}
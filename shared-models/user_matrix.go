@@ -62,6 +62,8 @@ type Role struct {
 
 	// Computed field (Not stored in DB)
 	SidebarItems []*SidebarItem `gorm:"-" json:"sidebar_items"`
+
+	BaseModel
 }
 
 // TableName overrides the default table name
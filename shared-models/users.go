@@ -10,14 +10,14 @@ import (
 // @swagger:model
 type WebUser struct {
 	ID                 int               `gorm:"primarykey;autoIncrement" json:"id"`
-	Email              string            `json:"email" gorm:"not null;unique" `
+	Email              string            `json:"email" gorm:"not null;uniqueIndex:idx_web_user_email,where:deleted_at IS NULL"` // Unique among users not deleted
 	IsVerified         bool              `json:"is_verified" gorm:"default:false"`
 	Token              string            `json:"token,omitempty"`
 	FullName           string            `json:"full_name"`
 	IsLock             string            `json:"is_lock" gorm:"default:0"`
 	MobileNo           string            `json:"mobile_no"`
 	MustChangePassword string            `json:"must_change_password" gorm:"default:0"`
-	UserName           string            `json:"user_name" gorm:"not null;unique" `
+	UserName           string            `json:"user_name" gorm:"not null;uniqueIndex:idx_web_user_user_name,where:deleted_at IS NULL"`
 	Password           string            `json:"password"`
 	PwdExpiredDate     time.Time         `json:"pwd_expired_date,omitempty"`
 	Status             string            `json:"status" gorm:"default:1"`
@@ -28,16 +28,14 @@ type WebUser struct {
 	MiddleName         string            `json:"middle_name"`
 	LastName           string            `json:"last_name"`
 	Birthday           time.Time         `json:"birthday,omitempty"`
-	CreatedBy          string            `json:"created_by"`
-	UpdatedBy          string            `json:"updated_by"`
 
 	// Corrected foreign key references
 	UserImage    UserImage      `json:"user_images" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Notification []Notification `json:"notifications" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Address      Address        `json:"address" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Devices      []UserDevice   `json:"devices,omitempty" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 
-	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime;type:timestamptz"`
+	BaseModel
 }
 
 // TableName overrides the default table name
@@ -0,0 +1,73 @@
+package sharedModels
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// WebhookSubscription is an external endpoint notified of events. It is disabled automatically after
+// too many consecutive failed attempts; its pending deliveries wait until it is enabled again.
+type WebhookSubscription struct {
+	ID                  int64                       `gorm:"primaryKey;autoIncrement" json:"id"`
+	URL                 string                      `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret              string                      `gorm:"type:varchar(255);not null" json:"-"` // HMAC-SHA256 key of the signatures
+	Events              datatypes.JSONSlice[string] `gorm:"type:jsonb;not null" json:"events"`   // Event types, or "*" for all
+	Description         string                      `json:"description,omitempty"`
+	Active              bool                        `gorm:"not null;index" json:"active"`
+	ConsecutiveFailures int                         `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time                  `gorm:"type:timestamptz" json:"disabled_at,omitempty"`
+	DisabledReason      string                      `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time                   `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	UpdatedAt           time.Time                   `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+}
+
+// TableName explicitly sets the table name
+func (WebhookSubscription) TableName() string {
+	return "v1.webhook_subscription"
+}
+
+// WebhookDelivery is an event queued for one subscription. Rows stay in the table once delivered, as the
+// delivery log of the event.
+type WebhookDelivery struct {
+	ID             int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID int64          `gorm:"not null;index" json:"subscription_id"`
+	EventID        string         `gorm:"type:varchar(64);not null;index" json:"event_id"` // The same for every subscription and attempt
+	EventType      string         `gorm:"type:varchar(100);not null;index" json:"event_type"`
+	Payload        datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string         `gorm:"type:varchar(20);not null;default:pending;index" json:"status"` // "pending", "sending", "delivered" or "dead"
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts    int            `gorm:"not null;default:8" json:"max_attempts"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	NextAttemptAt  time.Time      `gorm:"type:timestamptz;not null;index" json:"next_attempt_at"`
+	LockedUntil    *time.Time     `gorm:"type:timestamptz" json:"-"` // Lease of the worker sending it
+	DeliveredAt    *time.Time     `gorm:"type:timestamptz" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"autoUpdateTime;type:timestamptz" json:"updated_at"`
+
+	Subscription *WebhookSubscription `json:"-" gorm:"foreignKey:SubscriptionID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	AttemptLog   []WebhookAttempt     `json:"attempt_log,omitempty" gorm:"foreignKey:DeliveryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// TableName explicitly sets the table name
+func (WebhookDelivery) TableName() string {
+	return "v1.webhook_delivery"
+}
+
+// WebhookAttempt records one attempt to send a WebhookDelivery.
+type WebhookAttempt struct {
+	ID           int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeliveryID   int64     `gorm:"not null;index" json:"delivery_id"`
+	Attempt      int       `gorm:"not null" json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"` // Truncated
+	Duration     int64     `json:"duration_ms"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;type:timestamptz" json:"created_at"`
+}
+
+// TableName explicitly sets the table name
+func (WebhookAttempt) TableName() string {
+	return "v1.webhook_attempt"
+}
@@ -0,0 +1,16 @@
+package sharedModels
+
+import "time"
+
+// WebSocketOverflow holds a WebSocket backplane message too large for a NOTIFY payload. The notification
+// only carries the row ID; rows are short-lived and removed by the publishing side.
+type WebSocketOverflow struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Payload   []byte    `gorm:"type:bytea;not null" json:"payload"`
+	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamptz;index" json:"created_at"`
+}
+
+// TableName explicitly sets the table name
+func (WebSocketOverflow) TableName() string {
+	return "v1.websocket_overflow"
+}
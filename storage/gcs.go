@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCS is a Storage on a Google Cloud Storage bucket.
+type GCS struct {
+	Client  *gcs.Client
+	Bucket  string
+	BaseURL string // Public URL prefix, defaults to https://storage.googleapis.com/<bucket>
+}
+
+// NewGCS returns a GCS storage on bucket using client, e.g. config.InitGoogleCloud().
+func NewGCS(client *gcs.Client, bucket string) *GCS {
+	return &GCS{Client: client, Bucket: bucket, BaseURL: "https://storage.googleapis.com/" + bucket}
+}
+
+func (s *GCS) object(key string) (*gcs.ObjectHandle, string, error) {
+	key, err := ValidateKey(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.Client.Bucket(s.Bucket).Object(key), key, nil
+}
+
+func (s *GCS) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*Object, error) {
+	obj, key, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType, r = DetectContentType(key, r)
+	}
+
+	w := obj.NewWriter(ctx)
+	w.ContentType = opts.ContentType
+	w.CacheControl = opts.CacheControl
+	w.Metadata = opts.Metadata
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return fromAttrs(w.Attrs()), nil
+}
+
+func (s *GCS) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	obj, _, err := s.object(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The reader does not carry the metadata: read the attributes, then that generation of the object
+	attrs, err := obj.Attrs(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := obj.Generation(attrs.Generation).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, fromAttrs(attrs), nil
+}
+
+func (s *GCS) Delete(ctx context.Context, key string) error {
+	obj, _, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	if err := obj.Delete(ctx); err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *GCS) Exists(ctx context.Context, key string) (bool, error) {
+	obj, _, err := s.object(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = obj.Attrs(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *GCS) List(ctx context.Context, prefix string) ([]Object, error) {
+	it := s.Client.Bucket(s.Bucket).Objects(ctx, &gcs.Query{Prefix: strings.TrimLeft(prefix, "/")})
+
+	var objects []Object
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, *fromAttrs(attrs))
+	}
+}
+
+// SignedURL returns a V4 signed URL, using the credentials of the client to sign it.
+func (s *GCS) SignedURL(ctx context.Context, key string, opts SignOptions) (string, error) {
+	key, err := ValidateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.Client.Bucket(s.Bucket).SignedURL(key, &gcs.SignedURLOptions{
+		Scheme:      gcs.SigningSchemeV4,
+		Method:      opts.method(),
+		Expires:     time.Now().Add(opts.expires()),
+		ContentType: opts.ContentType,
+	})
+}
+
+// SignedUploadURL returns a V4 signed PUT URL. The size limit is enforced by GCS through the signed
+// x-goog-content-length-range header.
+func (s *GCS) SignedUploadURL(ctx context.Context, key string, opts SignOptions) (*UploadURL, error) {
+	key, err := ValidateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := time.Now().Add(opts.expires())
+	upload := &UploadURL{Key: key, Method: http.MethodPut, Headers: map[string]string{}, ExpiresAt: expires}
+	signOpts := &gcs.SignedURLOptions{
+		Scheme:      gcs.SigningSchemeV4,
+		Method:      http.MethodPut,
+		Expires:     expires,
+		ContentType: opts.ContentType,
+	}
+	if opts.ContentType != "" {
+		upload.Headers["Content-Type"] = opts.ContentType
+	}
+	if opts.MaxSize > 0 {
+		limit := "0," + strconv.FormatInt(opts.MaxSize, 10)
+		signOpts.Headers = []string{"x-goog-content-length-range:" + limit}
+		upload.Headers["X-Goog-Content-Length-Range"] = limit
+	}
+
+	upload.URL, err = s.Client.Bucket(s.Bucket).SignedURL(key, signOpts)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (s *GCS) Copy(ctx context.Context, srcKey, dstKey string) (*Object, error) {
+	src, _, err := s.object(srcKey)
+	if err != nil {
+		return nil, err
+	}
+	dst, _, err := s.object(dstKey)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := dst.CopierFrom(src).Run(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromAttrs(attrs), nil
+}
+
+func (s *GCS) URL(key string) string {
+	return strings.TrimRight(s.BaseURL, "/") + "/" + escapeKey(key)
+}
+
+func fromAttrs(attrs *gcs.ObjectAttrs) *Object {
+	return &Object{
+		Key:         attrs.Name,
+		ContentType: attrs.ContentType,
+		Size:        attrs.Size,
+		Metadata:    attrs.Metadata,
+		UpdatedAt:   attrs.Updated,
+	}
+}
+
+// escapeKey escapes every segment of key for use in a URL path.
+func escapeKey(key string) string {
+	parts := strings.Split(strings.TrimLeft(key, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,348 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaDir holds the content type and metadata of local objects, mirroring the object tree.
+const metaDir = ".meta"
+
+// Local is a Storage on the local filesystem, for development and tests. Objects are served
+// under BaseURL by Handler, and SignedURL signs URLs with Secret.
+type Local struct {
+	Root    string
+	BaseURL string
+	Secret  []byte
+	Public  bool // Serve downloads without a signature, like a public bucket
+}
+
+// localMeta is stored next to each object.
+type localMeta struct {
+	ContentType  string            `json:"content_type"`
+	CacheControl string            `json:"cache_control,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocal returns a Local storage under root, creating it if needed. Without a secret a random one is
+// generated, so signed URLs do not survive a restart.
+func NewLocal(root, baseURL string, secret []byte) (*Local, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		log.Println("⚠️ STORAGE_SIGNING_KEY is not set, local signed URLs expire on restart")
+	}
+	return &Local{Root: root, BaseURL: baseURL, Secret: secret}, nil
+}
+
+func (s *Local) paths(key string) (string, string, string, error) {
+	key, err := ValidateKey(key)
+	if err != nil {
+		return "", "", "", err
+	}
+	if key == metaDir || strings.HasPrefix(key, metaDir+"/") {
+		return "", "", "", ErrInvalidKey
+	}
+	file := filepath.Join(s.Root, filepath.FromSlash(key))
+	meta := filepath.Join(s.Root, metaDir, filepath.FromSlash(key)+".json")
+	return key, file, meta, nil
+}
+
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*Object, error) {
+	key, file, metaFile, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ContentType == "" {
+		opts.ContentType, r = DetectContentType(key, r)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	// Write to a temporary file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: r}); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	meta, err := json.Marshal(localMeta{ContentType: opts.ContentType, CacheControl: opts.CacheControl, Metadata: opts.Metadata})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(metaFile), 0o755); err != nil {
+		return nil, err
+	}
+	// The object is in place before its metadata: a failed Put leaves the previous metadata with the
+	// previous object rather than describing an object that was never written
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(metaFile, meta, 0o644); err != nil {
+		return nil, err
+	}
+	return s.stat(key, file, metaFile)
+}
+
+func (s *Local) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	key, file, metaFile, err := s.paths(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	obj, err := s.stat(key, file, metaFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, obj, nil
+}
+
+func (s *Local) Delete(ctx context.Context, key string) error {
+	_, file, metaFile, err := s.paths(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(metaFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Local) Exists(ctx context.Context, key string) (bool, error) {
+	_, file, _, err := s.paths(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil && info.Mode().IsRegular(), err
+}
+
+func (s *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	prefix = strings.TrimLeft(prefix, "/")
+
+	// Only walk the directory the prefix points into
+	start := s.Root
+	if dir := prefix[:strings.LastIndex(prefix, "/")+1]; dir != "" {
+		start = filepath.Join(s.Root, filepath.FromSlash(dir))
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(start, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == metaDir && filepath.Dir(file) == filepath.Clean(s.Root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Root, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		_, _, metaFile, err := s.paths(key)
+		if err != nil {
+			return nil
+		}
+		obj, err := s.stat(key, file, metaFile)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *obj)
+		return ctx.Err()
+	})
+	return objects, err
+}
+
+func (s *Local) Copy(ctx context.Context, srcKey, dstKey string) (*Object, error) {
+	r, obj, err := s.Get(ctx, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	_, _, metaFile, _ := s.paths(srcKey)
+	meta := s.meta(metaFile)
+	return s.Put(ctx, dstKey, r, PutOptions{ContentType: obj.ContentType, CacheControl: meta.CacheControl, Metadata: obj.Metadata})
+}
+
+// SignedURL returns a URL under BaseURL carrying an HMAC signature of the method, key, expiry and constraints.
+func (s *Local) SignedURL(ctx context.Context, key string, opts SignOptions) (string, error) {
+	key, _, _, err := s.paths(key)
+	if err != nil {
+		return "", err
+	}
+	return s.signedURL(key, opts, time.Now().Add(opts.expires())), nil
+}
+
+// SignedUploadURL returns a signed PUT URL. Handler enforces its content type and size.
+func (s *Local) SignedUploadURL(ctx context.Context, key string, opts SignOptions) (*UploadURL, error) {
+	key, _, _, err := s.paths(key)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Method = http.MethodPut
+	expires := time.Now().Add(opts.expires())
+	upload := &UploadURL{
+		Key:       key,
+		URL:       s.signedURL(key, opts, expires),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{},
+		ExpiresAt: expires,
+	}
+	if opts.ContentType != "" {
+		upload.Headers["Content-Type"] = opts.ContentType
+	}
+	return upload, nil
+}
+
+func (s *Local) signedURL(key string, opts SignOptions, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	maxSize := strconv.FormatInt(max(opts.MaxSize, 0), 10)
+
+	query := url.Values{}
+	query.Set("method", opts.method())
+	query.Set("expires", expires)
+	if opts.ContentType != "" {
+		query.Set("content_type", opts.ContentType)
+	}
+	if opts.MaxSize > 0 {
+		query.Set("max_size", maxSize)
+	}
+	query.Set("signature", s.sign(opts.method(), key, expires, opts.ContentType, maxSize))
+	return s.URL(key) + "?" + query.Encode()
+}
+
+// Verify checks the signature of a request for key with the given method and query, and returns
+// the constraints it was signed with.
+func (s *Local) Verify(method, key string, query url.Values) (SignOptions, error) {
+	opts := SignOptions{Method: query.Get("method"), ContentType: query.Get("content_type")}
+	if opts.Method == "" || !strings.EqualFold(opts.Method, method) {
+		return opts, ErrSignature
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return opts, ErrSignature
+	}
+	if size := query.Get("max_size"); size != "" {
+		if opts.MaxSize, err = strconv.ParseInt(size, 10, 64); err != nil || opts.MaxSize <= 0 {
+			return opts, ErrSignature
+		}
+	}
+
+	expected := s.sign(opts.method(), key, query.Get("expires"), opts.ContentType, strconv.FormatInt(opts.MaxSize, 10))
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return opts, ErrSignature
+	}
+	return opts, nil
+}
+
+func (s *Local) sign(method, key, expires, contentType, maxSize string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + expires + "\n" + contentType + "\n" + maxSize))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Local) URL(key string) string {
+	return strings.TrimRight(s.BaseURL, "/") + "/" + escapeKey(key)
+}
+
+func (s *Local) stat(key, file, metaFile string) (*Object, error) {
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	meta := s.meta(metaFile)
+	if meta.ContentType == "" {
+		meta.ContentType = "application/octet-stream"
+	}
+	return &Object{
+		Key:         key,
+		ContentType: meta.ContentType,
+		Size:        info.Size(),
+		Metadata:    meta.Metadata,
+		UpdatedAt:   info.ModTime(),
+	}, nil
+}
+
+func (s *Local) meta(metaFile string) localMeta {
+	var meta localMeta
+	if data, err := os.ReadFile(metaFile); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// contextReader stops a copy when its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
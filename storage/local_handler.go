@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Handler serves the objects of s like a bucket would: GET and HEAD download an object, PUT uploads
+// one through a URL from SignedUploadURL. Mount it under the path of BaseURL, e.g.
+//
+//	app.All("/files/*", local.Handler)
+//
+// Downloads need a signature unless Public is set. Enable the app's StreamRequestBody to stream uploads.
+func (s *Local) Handler(c fiber.Ctx) error {
+	key, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return helper.JSONResponse(c.Status(fiber.StatusBadRequest), respcode.ERR_CODE_400, ErrInvalidKey.Error())
+	}
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return helper.JSONResponse(c.Status(fiber.StatusBadRequest), respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG)
+	}
+
+	method := c.Method()
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPut {
+		return helper.JSONResponse(c.Status(fiber.StatusMethodNotAllowed), respcode.ERR_CODE_405, respcode.ERR_CODE_405_MSG)
+	}
+
+	var opts SignOptions
+	if method == http.MethodPut || !s.Public || query.Has("signature") {
+		if opts, err = s.Verify(method, key, query); err != nil {
+			return helper.JSONResponse(c.Status(fiber.StatusForbidden), respcode.ERR_CODE_403, err.Error())
+		}
+	}
+
+	if method == http.MethodPut {
+		return s.serveUpload(c, key, opts)
+	}
+	return s.serveDownload(c, key)
+}
+
+func (s *Local) serveDownload(c fiber.Ctx, key string) error {
+	r, obj, err := s.Get(c.Context(), key)
+	if err != nil {
+		return storageError(c, err)
+	}
+
+	_, _, metaFile, _ := s.paths(key)
+	if cacheControl := s.meta(metaFile).CacheControl; cacheControl != "" {
+		c.Set(fiber.HeaderCacheControl, cacheControl)
+	}
+	c.Set(fiber.HeaderContentType, obj.ContentType)
+	c.Set(fiber.HeaderLastModified, obj.UpdatedAt.UTC().Format(http.TimeFormat))
+	return c.SendStream(r, int(obj.Size))
+}
+
+func (s *Local) serveUpload(c fiber.Ctx, key string, opts SignOptions) error {
+	contentType := c.Get(fiber.HeaderContentType)
+	if opts.ContentType != "" && contentType != opts.ContentType {
+		return helper.JSONResponse(c.Status(fiber.StatusForbidden), respcode.ERR_CODE_403, "Content-Type does not match the signed URL")
+	}
+	if opts.MaxSize > 0 && int64(c.Request().Header.ContentLength()) > opts.MaxSize {
+		return helper.JSONResponse(c.Status(fiber.StatusRequestEntityTooLarge), respcode.ERR_CODE_413, respcode.ERR_CODE_413_MSG)
+	}
+
+	var body io.Reader = bytes.NewReader(c.Body())
+	if stream := c.Request().BodyStream(); stream != nil {
+		body = stream
+	}
+	if opts.MaxSize > 0 {
+		// Content-Length may be missing with chunked uploads
+		body = &limitReader{r: body, n: opts.MaxSize}
+	}
+
+	obj, err := s.Put(c.Context(), key, body, PutOptions{ContentType: contentType})
+	if err != nil {
+		return storageError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, obj)
+}
+
+func storageError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return helper.JSONResponse(c.Status(fiber.StatusNotFound), respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, ErrInvalidKey):
+		return helper.JSONResponse(c.Status(fiber.StatusBadRequest), respcode.ERR_CODE_400, err.Error())
+	case errors.Is(err, ErrTooLarge):
+		return helper.JSONResponse(c.Status(fiber.StatusRequestEntityTooLarge), respcode.ERR_CODE_413, respcode.ERR_CODE_413_MSG)
+	default:
+		return helper.JSONResponseWithError(c.Status(fiber.StatusInternalServerError), respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+}
+
+// limitReader fails with ErrTooLarge once more than n bytes are read, so the partial object is discarded.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
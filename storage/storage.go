@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DevdotSP/go-utils/config"
+	"github.com/DevdotSP/go-utils/utils"
+)
+
+// Storage errors
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+	ErrSignature  = errors.New("invalid or expired signature")
+	ErrTooLarge   = errors.New("object exceeds the maximum size")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key         string            `json:"key"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// PutOptions describe an object being written. ContentType is detected from the key extension and
+// the first bytes of the content when empty.
+type PutOptions struct {
+	ContentType  string
+	CacheControl string
+	Metadata     map[string]string
+}
+
+// SignOptions describe a signed URL. Method defaults to GET and Expires to 15 minutes.
+type SignOptions struct {
+	Method      string
+	Expires     time.Duration
+	ContentType string // Required Content-Type of a signed PUT
+	MaxSize     int64  // Maximum size in bytes of a signed PUT, unlimited when 0
+}
+
+// UploadURL is a signed URL a client uploads one object to directly, without going through the API.
+// The request must use Method and carry every header in Headers.
+type UploadURL struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Storage stores objects by key, e.g. "users/12/avatar.jpg". Implementations stream content
+// instead of buffering it.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*Object, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
+	SignedURL(ctx context.Context, key string, opts SignOptions) (string, error)
+	// SignedUploadURL returns a signed PUT URL constrained by the ContentType, MaxSize and Expires of opts
+	SignedUploadURL(ctx context.Context, key string, opts SignOptions) (*UploadURL, error)
+	Copy(ctx context.Context, srcKey, dstKey string) (*Object, error)
+	// URL returns the public URL of key
+	URL(key string) string
+}
+
+var (
+	Default  Storage
+	initOnce sync.Once
+)
+
+// Init selects Default from the environment: STORAGE_DRIVER "gcs" (the default) uses config.InitGoogleCloud
+// and STORAGE_BUCKET, "local" writes under STORAGE_LOCAL_ROOT and serves unsigned downloads unless
+// STORAGE_LOCAL_PUBLIC is "false". STORAGE_BASE_URL overrides the public URL prefix.
+func Init() Storage {
+	initOnce.Do(func() {
+		s, err := New(context.Background(), utils.GetEnv("STORAGE_DRIVER", "gcs"))
+		if err != nil {
+			log.Fatalf("❌ Failed to initialize storage: %v", err)
+		}
+		Default = s
+		log.Println("✅ Storage initialized")
+	})
+	return Default
+}
+
+// New returns the Storage of driver configured from the environment.
+func New(ctx context.Context, driver string) (Storage, error) {
+	switch driver {
+	case "gcs":
+		bucket := utils.GetEnv("STORAGE_BUCKET", "")
+		if bucket == "" {
+			return nil, errors.New("STORAGE_BUCKET is required for the gcs driver")
+		}
+		s := NewGCS(config.InitGoogleCloud(), bucket)
+		s.BaseURL = utils.GetEnv("STORAGE_BASE_URL", s.BaseURL)
+		return s, nil
+	case "local":
+		s, err := NewLocal(
+			utils.GetEnv("STORAGE_LOCAL_ROOT", "uploads"),
+			utils.GetEnv("STORAGE_BASE_URL", "/files"),
+			[]byte(utils.GetEnv("STORAGE_SIGNING_KEY", "")),
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.Public = utils.GetEnv("STORAGE_LOCAL_PUBLIC", "true") == "true"
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// ValidateKey rejects empty keys and keys escaping their prefix, and returns key without leading slashes.
+func ValidateKey(key string) (string, error) {
+	key = strings.TrimLeft(key, "/")
+	if key == "" || strings.HasSuffix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+// DetectContentType returns the content type of key from its extension or, failing that, by sniffing r.
+// The returned reader must be used in place of r.
+func DetectContentType(key string, r io.Reader) (string, io.Reader) {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" && contentType != "application/octet-stream" {
+		return contentType, r
+	}
+
+	buffered := bufio.NewReaderSize(r, 512)
+	head, _ := buffered.Peek(512)
+	return http.DetectContentType(head), buffered
+}
+
+func (o SignOptions) method() string {
+	if o.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(o.Method)
+}
+
+func (o SignOptions) expires() time.Duration {
+	if o.Expires <= 0 {
+		return 15 * time.Minute
+	}
+	return o.Expires
+}
@@ -0,0 +1,227 @@
+package sysparam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// History actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// ParameterRequest creates or replaces a parameter. Type defaults to "string" on create and to the
+// current type on update.
+type ParameterRequest struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// List returns the parameters whose key starts with prefix (all when empty), by key.
+func (s *Service) List(ctx context.Context, prefix string) ([]sharedModels.SystemParameter, error) {
+	query := s.DB.WithContext(ctx).Order("key")
+	if prefix != "" {
+		query = query.Where("key LIKE ?", escapeLike(prefix)+"%")
+	}
+	params := []sharedModels.SystemParameter{}
+	if err := query.Find(&params).Error; err != nil {
+		return nil, fmt.Errorf("failed to list system parameters: %w", err)
+	}
+	return params, nil
+}
+
+// Get returns a parameter from the table, bypassing the cache.
+func (s *Service) Get(ctx context.Context, key string) (*sharedModels.SystemParameter, error) {
+	var param sharedModels.SystemParameter
+	err := s.DB.WithContext(ctx).Where("key = ?", key).Take(&param).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system parameter %s: %w", key, err)
+	}
+	return &param, nil
+}
+
+// Create adds a parameter. The actor of ctx is recorded in its history.
+func (s *Service) Create(ctx context.Context, req ParameterRequest) (*sharedModels.SystemParameter, error) {
+	return s.save(ctx, req, ActionCreate)
+}
+
+// Update replaces the value, type and description of the parameter key.
+func (s *Service) Update(ctx context.Context, key string, req ParameterRequest) (*sharedModels.SystemParameter, error) {
+	req.Key = key
+	return s.save(ctx, req, ActionUpdate)
+}
+
+// Set creates or updates key with a Go value, typed as in SetDefault.
+func (s *Service) Set(ctx context.Context, key string, value interface{}) (*sharedModels.SystemParameter, error) {
+	typ, text, err := format(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return s.save(ctx, ParameterRequest{Key: key, Value: text, Type: typ}, "")
+}
+
+// save writes a parameter with its history and notifies the change. An empty action creates or updates.
+func (s *Service) save(ctx context.Context, req ParameterRequest, action string) (*sharedModels.SystemParameter, error) {
+	req.Key = strings.TrimSpace(req.Key)
+	if req.Key == "" {
+		return nil, errors.New("system parameter key is required")
+	}
+
+	var param sharedModels.SystemParameter
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old sharedModels.SystemParameter
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", req.Key).Take(&old).Error
+		exists := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		switch {
+		case action == ActionCreate && exists:
+			return fmt.Errorf("%w: %s", ErrExists, req.Key)
+		case action == ActionUpdate && !exists:
+			return fmt.Errorf("%w: %s", ErrNotFound, req.Key)
+		}
+
+		if req.Type == "" {
+			req.Type = TypeString
+			if exists {
+				req.Type = old.Type
+			}
+		}
+		if err := validate(req.Type, req.Value); err != nil {
+			return err
+		}
+
+		actor := utils.ActorFromContext(ctx)
+		history := sharedModels.SystemParameterHistory{Key: req.Key, NewValue: &req.Value, NewType: req.Type, ChangedBy: actor}
+		param = sharedModels.SystemParameter{Key: req.Key, Value: req.Value, Type: req.Type, Description: req.Description, UpdatedBy: actor}
+		if exists {
+			history.Action, history.OldValue, history.OldType = ActionUpdate, &old.Value, old.Type
+			param.CreatedAt = old.CreatedAt
+			if action == "" {
+				param.Description = old.Description
+			}
+			err = tx.Save(&param).Error
+		} else {
+			history.Action = ActionCreate
+			// The lock above does not cover a key that does not exist yet
+			if err = tx.Create(&param).Error; errors.Is(err, gorm.ErrDuplicatedKey) || utils.IsUniqueConstraintError(err) {
+				return fmt.Errorf("%w: %s", ErrExists, req.Key)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("failed to record system parameter history: %w", err)
+		}
+		return s.notify(tx, req.Key)
+	})
+	if err != nil {
+		if errors.Is(err, ErrExists) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidType) || errors.Is(err, ErrInvalidValue) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save system parameter %s: %w", req.Key, err)
+	}
+	s.Invalidate(req.Key)
+	return &param, nil
+}
+
+// Delete removes the parameter key, which falls back to its default.
+func (s *Service) Delete(ctx context.Context, key string) error {
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var old sharedModels.SystemParameter
+		err := tx.Clauses(clause.Returning{}).Where("key = ?", key).Delete(&old).Error
+		if err != nil {
+			return err
+		}
+		if old.Key == "" {
+			return fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+
+		history := sharedModels.SystemParameterHistory{
+			Key:       key,
+			Action:    ActionDelete,
+			OldValue:  &old.Value,
+			OldType:   old.Type,
+			ChangedBy: utils.ActorFromContext(ctx),
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("failed to record system parameter history: %w", err)
+		}
+		return s.notify(tx, key)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete system parameter %s: %w", key, err)
+	}
+	s.Invalidate(key)
+	return nil
+}
+
+// HistoryPage is one page of parameter changes. Pass NextCursor back as the cursor to get the next page;
+// it is nil on the last page.
+type HistoryPage struct {
+	Items      []sharedModels.SystemParameterHistory `json:"items"`
+	NextCursor *int64                                `json:"next_cursor"`
+}
+
+// History returns the changes of key (of every parameter when empty), newest first. Deleted parameters
+// keep their history.
+func (s *Service) History(ctx context.Context, key string, cursor int64, limit int) (*HistoryPage, error) {
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := s.DB.WithContext(ctx).Model(&sharedModels.SystemParameterHistory{})
+	if key != "" {
+		query = query.Where("key = ?", key)
+	}
+	if cursor > 0 {
+		query = query.Where("id < ?", cursor)
+	}
+
+	var items []sharedModels.SystemParameterHistory
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to list system parameter history: %w", err)
+	}
+
+	page := &HistoryPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next := page.Items[limit-1].ID
+		page.NextCursor = &next
+	}
+	if page.Items == nil {
+		page.Items = []sharedModels.SystemParameterHistory{}
+	}
+	return page, nil
+}
+
+// notify tells the listeners of every instance that key changed, once tx commits.
+func (s *Service) notify(tx *gorm.DB, key string) error {
+	if err := tx.Exec("SELECT pg_notify(?, ?)", s.Channel, key).Error; err != nil {
+		return fmt.Errorf("failed to notify system parameter change: %w", err)
+	}
+	return nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,99 @@
+package sysparam
+
+import (
+	"errors"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/gofiber/fiber/v3"
+)
+
+// RegisterRoutes mounts the admin endpoints of the parameters on router, behind an admin-only middleware:
+// GET / lists parameters (?prefix=), POST / creates one, GET /-/history lists every change (?cursor=&limit=),
+// GET, PUT and DELETE /:key read, replace and delete one, and GET /:key/history lists its changes.
+// The "-" segment keeps the global history apart from a parameter whose key is "history".
+func (s *Service) RegisterRoutes(router fiber.Router) {
+	router.Get("/", s.ListHandler)
+	router.Post("/", s.CreateHandler)
+	router.Get("/-/history", s.HistoryHandler)
+	router.Get("/:key", s.GetHandler)
+	router.Put("/:key", s.UpdateHandler)
+	router.Delete("/:key", s.DeleteHandler)
+	router.Get("/:key/history", s.HistoryHandler)
+}
+
+// ListHandler returns the parameters.
+func (s *Service) ListHandler(c fiber.Ctx) error {
+	params, err := s.List(c.Context(), c.Query("prefix"))
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, params)
+}
+
+// GetHandler returns the parameter in :key.
+func (s *Service) GetHandler(c fiber.Ctx) error {
+	param, err := s.Get(c.Context(), c.Params("key"))
+	if err != nil {
+		return parameterError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, param)
+}
+
+// CreateHandler creates the parameter in a ParameterRequest body.
+func (s *Service) CreateHandler(c fiber.Ctx) error {
+	var req ParameterRequest
+	if err := c.Bind().Body(&req); err != nil || req.Key == "" {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Key is required")
+	}
+
+	param, err := s.Create(c.Context(), req)
+	if err != nil {
+		return parameterError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, param)
+}
+
+// UpdateHandler replaces the parameter in :key with a ParameterRequest body.
+func (s *Service) UpdateHandler(c fiber.Ctx) error {
+	var req ParameterRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG)
+	}
+
+	param, err := s.Update(c.Context(), c.Params("key"), req)
+	if err != nil {
+		return parameterError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, param)
+}
+
+// DeleteHandler deletes the parameter in :key.
+func (s *Service) DeleteHandler(c fiber.Ctx) error {
+	if err := s.Delete(c.Context(), c.Params("key")); err != nil {
+		return parameterError(c, err)
+	}
+	return helper.JSONResponse(c, respcode.SUC_CODE_DELETE, respcode.SUC_CODE_DELETE_MSG)
+}
+
+// HistoryHandler returns one page of the changes of the parameter in :key, or of every parameter.
+func (s *Service) HistoryHandler(c fiber.Ctx) error {
+	page, err := s.History(c.Context(), c.Params("key"), fiber.Query[int64](c, "cursor", 0), fiber.Query[int](c, "limit", 20))
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, page)
+}
+
+// parameterError answers a failed parameter operation.
+func parameterError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, ErrExists):
+		return helper.JSONResponse(c, respcode.ERR_CODE_409, respcode.ERR_CODE_409_MSG)
+	case errors.Is(err, ErrInvalidType), errors.Is(err, ErrInvalidValue):
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, err.Error())
+	}
+	return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+}
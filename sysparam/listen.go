@@ -0,0 +1,90 @@
+package sysparam
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listen drops cached parameters as soon as they change on any instance, until ctx is done. Changes
+// are notified on Channel by the admin methods of Service, in the transaction of the change.
+func (s *Service) Listen(ctx context.Context) error {
+	if s.Pool == nil {
+		return errors.New("system parameter service has no connection pool to listen on")
+	}
+	conn, err := s.listen(ctx)
+	if err != nil {
+		return err
+	}
+	go s.run(ctx, conn)
+	return nil
+}
+
+// listen takes a connection out of the pool and starts listening on the channel. The connection
+// is never returned to the pool, as it stays subscribed.
+func (s *Service) listen(ctx context.Context) (*pgx.Conn, error) {
+	pooled, err := s.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn := pooled.Hijack()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{s.Channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
+// run waits for notifications until ctx is done, reconnecting when the connection is lost. Changes
+// missed while disconnected are covered by dropping the whole cache on reconnect.
+func (s *Service) run(ctx context.Context, conn *pgx.Conn) {
+	defer func() {
+		if conn != nil {
+			conn.Close(context.Background())
+		}
+	}()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			conn.Close(context.Background())
+			conn = nil
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Printf("⚠️ System parameter listener connection lost: %v", err)
+			if conn = s.reconnect(ctx); conn == nil {
+				return
+			}
+			s.Invalidate()
+			continue
+		}
+		s.Invalidate(notification.Payload)
+	}
+}
+
+func (s *Service) reconnect(ctx context.Context) *pgx.Conn {
+	delay := time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := s.listen(ctx)
+		if err == nil {
+			log.Println("✅ System parameter listener reconnected")
+			return conn
+		}
+		log.Printf("⚠️ System parameter listener reconnect failed: %v", err)
+		if delay < 30*time.Second {
+			delay *= 2
+		}
+	}
+}
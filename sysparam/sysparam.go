@@ -0,0 +1,235 @@
+package sysparam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/gorm"
+)
+
+// Parameter types
+const (
+	TypeString   = "string"
+	TypeInt      = "int"
+	TypeBool     = "bool"
+	TypeDuration = "duration" // Parsed by time.ParseDuration, e.g. "90s"
+	TypeJSON     = "json"
+)
+
+// DefaultChannel is the NOTIFY channel of parameter changes
+const DefaultChannel = "system_parameter"
+
+var (
+	ErrNotFound     = errors.New("system parameter not found")
+	ErrExists       = errors.New("system parameter already exists")
+	ErrInvalidType  = errors.New("invalid system parameter type")
+	ErrInvalidValue = errors.New("invalid system parameter value")
+)
+
+// Service reads typed system parameters through an in-memory cache. Entries expire after TTL; with a
+// Pool, Listen also drops them as soon as a parameter changes on any instance. Parameters missing from
+// the table fall back to the defaults set with SetDefault.
+type Service struct {
+	DB      *gorm.DB
+	Pool    *pgxpool.Pool // Connection pool used by Listen
+	Channel string
+	TTL     time.Duration
+
+	mu         sync.RWMutex
+	cache      map[string]entry
+	generation uint64 // Incremented by Invalidate, so a lookup racing it does not cache a stale value
+	defaults   map[string]string
+}
+
+// entry is a cached parameter, nil when it is not in the table
+type entry struct {
+	param   *sharedModels.SystemParameter
+	expires time.Time
+}
+
+// New returns a Service on db caching parameters for 5 minutes. pool may be nil when Listen is not used.
+func New(db *gorm.DB, pool *pgxpool.Pool) *Service {
+	return &Service{
+		DB:       db,
+		Pool:     pool,
+		Channel:  DefaultChannel,
+		TTL:      5 * time.Minute,
+		cache:    map[string]entry{},
+		defaults: map[string]string{},
+	}
+}
+
+// SetDefault sets the value of key while it is not in the table. value is a string, an int, a bool,
+// a time.Duration, or any other value encoded as JSON.
+func (s *Service) SetDefault(key string, value interface{}) error {
+	_, text, err := format(value)
+	if err != nil {
+		return fmt.Errorf("invalid default of %s: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults[key] = text
+	return nil
+}
+
+// String returns the value of key as is.
+func (s *Service) String(ctx context.Context, key string) (string, error) {
+	return s.value(ctx, key)
+}
+
+// Int returns the value of key as an int.
+func (s *Service) Int(ctx context.Context, key string) (int, error) {
+	value, err := s.value(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not an int", ErrInvalidValue, key)
+	}
+	return n, nil
+}
+
+// Bool returns the value of key as a bool: 1, t, true, 0, f or false in any case.
+func (s *Service) Bool(ctx context.Context, key string) (bool, error) {
+	value, err := s.value(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s is not a bool", ErrInvalidValue, key)
+	}
+	return b, nil
+}
+
+// Duration returns the value of key as a time.Duration.
+func (s *Service) Duration(ctx context.Context, key string) (time.Duration, error) {
+	value, err := s.value(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not a duration", ErrInvalidValue, key)
+	}
+	return d, nil
+}
+
+// JSON decodes the value of key into a T.
+func JSON[T any](ctx context.Context, s *Service, key string) (T, error) {
+	var result T
+	value, err := s.value(ctx, key)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return result, fmt.Errorf("%w: %s: %v", ErrInvalidValue, key, err)
+	}
+	return result, nil
+}
+
+// Invalidate drops keys from the cache, or every key when none is given.
+func (s *Service) Invalidate(keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	if len(keys) == 0 {
+		s.cache = map[string]entry{}
+		return
+	}
+	for _, key := range keys {
+		delete(s.cache, key)
+	}
+}
+
+// value returns the raw value of key from the cache, the table or the defaults.
+func (s *Service) value(ctx context.Context, key string) (string, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[key]
+	generation := s.generation
+	s.mu.RUnlock()
+
+	if !ok || time.Now().After(cached.expires) {
+		var param sharedModels.SystemParameter
+		err := s.DB.WithContext(ctx).Where("key = ?", key).Take(&param).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			cached = entry{}
+		case err != nil:
+			return "", fmt.Errorf("failed to read system parameter %s: %w", key, err)
+		default:
+			cached = entry{param: &param}
+		}
+		cached.expires = time.Now().Add(s.TTL)
+
+		s.mu.Lock()
+		if s.generation == generation {
+			s.cache[key] = cached
+		}
+		s.mu.Unlock()
+	}
+
+	if cached.param != nil {
+		return cached.param.Value, nil
+	}
+	s.mu.RLock()
+	value, ok := s.defaults[key]
+	s.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return value, nil
+}
+
+// format returns the type and text of a Go value.
+func format(value interface{}) (string, string, error) {
+	switch v := value.(type) {
+	case string:
+		return TypeString, v, nil
+	case int:
+		return TypeInt, strconv.Itoa(v), nil
+	case int64:
+		return TypeInt, strconv.FormatInt(v, 10), nil
+	case bool:
+		return TypeBool, strconv.FormatBool(v), nil
+	case time.Duration:
+		return TypeDuration, v.String(), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", "", err
+	}
+	return TypeJSON, string(data), nil
+}
+
+// validate checks that value parses as typ.
+func validate(typ, value string) error {
+	var err error
+	switch typ {
+	case TypeString:
+	case TypeInt:
+		_, err = strconv.Atoi(value)
+	case TypeBool:
+		_, err = strconv.ParseBool(value)
+	case TypeDuration:
+		_, err = time.ParseDuration(value)
+	case TypeJSON:
+		if !json.Valid([]byte(value)) {
+			err = errors.New("not valid JSON")
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidType, typ)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: not a %s", ErrInvalidValue, typ)
+	}
+	return nil
+}
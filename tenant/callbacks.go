@@ -0,0 +1,256 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+)
+
+// Register installs the callbacks that route statements to the tenant schema found in the statement
+// context. Statements without a tenant keep using BaseSchema, so single-tenant setups are unaffected.
+//
+// The SQL is rewritten when it is sent to the database, so every reference to BaseSchema is covered:
+// models with a `v1.` TableName, db.Table("v1.x"), Joins on associations, subqueries and hand-written
+// SQL, quoted ("v1"."x") or not (v1.x). String literals and comments are left untouched.
+func Register(db *gorm.DB) error {
+	r := newRewriter(db.Statement.Quote(BaseSchema))
+
+	if err := db.Callback().Create().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+	if err := db.Callback().Raw().Before("*").Register("tenant:schema", r.bind); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Create().After("*").Register("tenant:release", release); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("*").Register("tenant:release", release); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("*").Register("tenant:release", release); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("*").Register("tenant:release", release); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().After("*").Register("tenant:release", release); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("*").Register("tenant:release", release)
+}
+
+// rewriter swaps BaseSchema for a tenant schema in SQL
+type rewriter struct {
+	pattern *regexp.Regexp
+}
+
+func newRewriter(quotedBase string) *rewriter {
+	// BaseSchema used as a qualifier, quoted or not, and not the tail of another name
+	pattern := regexp.MustCompile(`(^|[^\w$".])(` + regexp.QuoteMeta(quotedBase) + `|(?i:` + regexp.QuoteMeta(BaseSchema) + `))\.`)
+	return &rewriter{pattern: pattern}
+}
+
+// bind makes the statement send its SQL through a pool rewriting it for the tenant of the context.
+func (r *rewriter) bind(db *gorm.DB) {
+	tenantID := utils.TenantFromContext(db.Statement.Context)
+	if tenantID == "" {
+		return
+	}
+	if err := ValidateID(tenantID); err != nil {
+		db.AddError(err)
+		return
+	}
+
+	schema := db.Statement.Quote(SchemaName(tenantID)) + "."
+	connPool, _ := unwrap(db.Statement.ConnPool)
+	db.Statement.ConnPool = wrap(connPool, func(sql string) string {
+		return r.rewrite(sql, schema)
+	})
+}
+
+// release restores the pool of the statement, which may be reused by a chained *gorm.DB, and rewrites
+// its SQL so the logger shows what was sent.
+func release(db *gorm.DB) {
+	connPool, rewrite := unwrap(db.Statement.ConnPool)
+	if rewrite == nil {
+		return
+	}
+	db.Statement.ConnPool = connPool
+	if sql := db.Statement.SQL.String(); sql != "" {
+		db.Statement.SQL.Reset()
+		db.Statement.SQL.WriteString(rewrite(sql))
+	}
+}
+
+// rewrite replaces the references to BaseSchema in the code of sql, skipping literals and comments.
+func (r *rewriter) rewrite(sql, schema string) string {
+	var b strings.Builder
+	for code, rest := "", sql; rest != ""; {
+		var skipped string
+		code, skipped, rest = nextLiteral(rest)
+		b.WriteString(r.pattern.ReplaceAllString(code, "${1}"+strings.ReplaceAll(schema, "$", "$$")))
+		b.WriteString(skipped)
+	}
+	return b.String()
+}
+
+// nextLiteral splits sql into the code before its first string literal or comment,
+// that literal, and the rest.
+func nextLiteral(sql string) (code, literal, rest string) {
+	for i := 0; i < len(sql); i++ {
+		var end int
+		switch {
+		case sql[i] == '\'':
+			end = literalEnd(sql, i, i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e'))
+		case strings.HasPrefix(sql[i:], "--"):
+			if end = strings.IndexByte(sql[i:], '\n'); end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 1
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			if end = strings.Index(sql[i+2:], "*/"); end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 4
+			}
+		case sql[i] == '$':
+			tag := dollarTag(sql[i:])
+			if tag == "" {
+				continue
+			}
+			if end = strings.Index(sql[i+len(tag):], tag); end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 2*len(tag)
+			}
+		default:
+			continue
+		}
+		return sql[:i], sql[i:end], sql[end:]
+	}
+	return sql, "", ""
+}
+
+// literalEnd returns the index after the string literal starting at start.
+func literalEnd(sql string, start int, escapes bool) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch {
+		case escapes && sql[i] == '\\':
+			i++
+		case sql[i] == '\'' && i+1 < len(sql) && sql[i+1] == '\'':
+			i++
+		case sql[i] == '\'':
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the start of sql, e.g. $$ or $body$,
+// or "" for anything else such as the $1 placeholder.
+func dollarTag(sql string) string {
+	for i := 1; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '$':
+			return sql[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9' || c >= 0x80:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// pool rewrites the SQL of every call before passing it to the wrapped pool. Transactions begun on it
+// are rewritten too.
+type pool struct {
+	gorm.ConnPool
+	rewrite func(string) string
+}
+
+// txPool is a pool over a transaction
+type txPool struct {
+	pool
+}
+
+func wrap(connPool gorm.ConnPool, rewrite func(string) string) gorm.ConnPool {
+	if _, ok := connPool.(gorm.TxCommitter); ok {
+		return &txPool{pool{ConnPool: connPool, rewrite: rewrite}}
+	}
+	return &pool{ConnPool: connPool, rewrite: rewrite}
+}
+
+// unwrap returns the pool wrapped by connPool with its rewrite, or connPool and nil when it is not wrapped.
+func unwrap(connPool gorm.ConnPool) (gorm.ConnPool, func(string) string) {
+	switch p := connPool.(type) {
+	case *pool:
+		return p.ConnPool, p.rewrite
+	case *txPool:
+		return p.ConnPool, p.rewrite
+	}
+	return connPool, nil
+}
+
+func (p *pool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.ConnPool.PrepareContext(ctx, p.rewrite(query))
+}
+
+func (p *pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, p.rewrite(query), args...)
+}
+
+func (p *pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, p.rewrite(query), args...)
+}
+
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, p.rewrite(query), args...)
+}
+
+// BeginTx starts a transaction on the wrapped pool, e.g. the one GORM opens around a Create.
+func (p *pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var tx gorm.ConnPool
+	var err error
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		var sqlTx *sql.Tx
+		if sqlTx, err = beginner.BeginTx(ctx, opts); err == nil {
+			tx = sqlTx
+		}
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wrap(tx, p.rewrite), nil
+}
+
+func (p *txPool) Commit() error {
+	return p.ConnPool.(gorm.TxCommitter).Commit()
+}
+
+func (p *txPool) Rollback() error {
+	return p.ConnPool.(gorm.TxCommitter).Rollback()
+}
@@ -0,0 +1,151 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/DevdotSP/go-utils/utils"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Source is a place the tenant of a request can be read from.
+type Source string
+
+const (
+	SourceHeader    Source = "header" // Only for tokens without a tenant claim, checked by Config.Member
+	SourceClaim     Source = "claim"
+	SourceSubdomain Source = "subdomain"
+)
+
+// Config controls how Middleware resolves the tenant of a request.
+type Config struct {
+	Sources    []Source // Tried in order, a tenant claim always wins; defaults to claim, subdomain
+	Header     string   // Defaults to X-Tenant-ID
+	Claim      string   // JWT claim set by the token issuer; defaults to utils.ClaimTenant
+	BaseDomain string   // acme.admin.example.com resolves to "acme" when BaseDomain is admin.example.com
+	Required   bool     // Reject requests without a tenant instead of using BaseSchema
+
+	// Exists rejects unknown tenants when set, e.g. a closure over tenant.Exists
+	Exists func(ctx context.Context, tenantID string) (bool, error)
+
+	// Member lets a token without a tenant claim use the tenant from the header or subdomain, e.g. by
+	// looking up the user's memberships. claims is nil on requests without a token. Without Member,
+	// such requests are rejected.
+	Member func(ctx context.Context, claims jwt.MapClaims, tenantID string) (bool, error)
+}
+
+// Middleware resolves the tenant of the request and stores it in the request context, so queries run
+// with db.WithContext(c.Context()) use the tenant schema. The tenant claim of the JWT is authoritative:
+// a header or subdomain naming another tenant is rejected, and one used by a token without the claim is
+// only accepted when Member approves it. Register it after JWTAuthMiddleware.
+func Middleware(cfg Config) fiber.Handler {
+	if len(cfg.Sources) == 0 {
+		cfg.Sources = []Source{SourceClaim, SourceSubdomain}
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Tenant-ID"
+	}
+	if cfg.Claim == "" {
+		cfg.Claim = utils.ClaimTenant
+	}
+
+	return func(c fiber.Ctx) error {
+		claims, _ := c.Locals("claims").(jwt.MapClaims)
+		claimed := fromClaim(claims, cfg.Claim)
+
+		tenantID := claimed
+		for _, source := range cfg.Sources {
+			requested := resolve(c, cfg, source)
+			if requested == "" {
+				continue
+			}
+			if claimed != "" && requested != claimed {
+				return helper.JSONResponse(c, respcode.ERR_CODE_403, "Token is not valid for this tenant")
+			}
+			if tenantID == "" {
+				tenantID = requested
+			}
+		}
+
+		if tenantID == "" {
+			if cfg.Required {
+				return helper.JSONResponse(c, respcode.ERR_CODE_400, "Tenant is required")
+			}
+			return c.Next()
+		}
+
+		if err := ValidateID(tenantID); err != nil {
+			return helper.JSONResponse(c, respcode.ERR_CODE_400, err.Error())
+		}
+
+		if claimed == "" {
+			ok := false
+			if cfg.Member != nil {
+				var err error
+				if ok, err = cfg.Member(c.Context(), claims, tenantID); err != nil {
+					return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+				}
+			}
+			if !ok {
+				return helper.JSONResponse(c, respcode.ERR_CODE_403, "Token is not valid for this tenant")
+			}
+		}
+
+		if cfg.Exists != nil {
+			ok, err := cfg.Exists(c.Context(), tenantID)
+			if err != nil {
+				return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+			}
+			if !ok {
+				return helper.JSONResponse(c, respcode.ERR_CODE_404, fmt.Sprintf("%v: %s", ErrUnknownTenant, tenantID))
+			}
+		}
+
+		c.SetContext(utils.WithTenant(c.Context(), tenantID))
+		return c.Next()
+	}
+}
+
+func resolve(c fiber.Ctx, cfg Config, source Source) string {
+	switch source {
+	case SourceHeader:
+		return strings.ToLower(strings.TrimSpace(c.Get(cfg.Header)))
+	case SourceClaim:
+		claims, _ := c.Locals("claims").(jwt.MapClaims)
+		return fromClaim(claims, cfg.Claim)
+	case SourceSubdomain:
+		return fromSubdomain(c.Hostname(), cfg.BaseDomain)
+	}
+	return ""
+}
+
+func fromClaim(claims jwt.MapClaims, claim string) string {
+	tenantID, _ := claims[claim].(string)
+	return strings.ToLower(tenantID)
+}
+
+func fromSubdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	host = strings.ToLower(host)
+	suffix := "." + strings.ToLower(baseDomain)
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+
+	sub := strings.TrimSuffix(host, suffix)
+	if strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// FromContext returns the tenant resolved by Middleware, or "" for requests using BaseSchema.
+func FromContext(c fiber.Ctx) string {
+	return utils.TenantFromContext(c.Context())
+}
@@ -0,0 +1,161 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Migrate creates the schema of tenantID if needed and auto-migrates models into it.
+// Models outside BaseSchema are shared between tenants and skipped.
+func Migrate(db *gorm.DB, tenantID string, models ...interface{}) error {
+	if err := ValidateID(tenantID); err != nil {
+		return err
+	}
+
+	scoped := Scoped(db, tenantID)
+	if err := scoped.Exec("CREATE SCHEMA IF NOT EXISTS " + db.Statement.Quote(SchemaName(tenantID))).Error; err != nil {
+		return fmt.Errorf("failed to create schema for tenant %s: %w", tenantID, err)
+	}
+
+	// Table() makes the migrator inspect the tenant schema, but GORM cannot order dependencies of a
+	// model bound to a custom table, so tables are created first and foreign keys added afterwards
+	tables := scoped.Session(&gorm.Session{})
+	config := *tables.Config
+	config.IgnoreRelationshipsWhenMigrating = true
+	tables.Config = &config
+
+	targets := make(map[interface{}]string, len(models))
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+
+		schema, table, ok := strings.Cut(stmt.Schema.Table, ".")
+		if !ok || schema != BaseSchema {
+			continue
+		}
+		targets[model] = SchemaName(tenantID) + "." + table
+
+		if err := tables.Table(targets[model]).AutoMigrate(model); err != nil {
+			return fmt.Errorf("failed to migrate %s for tenant %s: %w", stmt.Schema.Table, tenantID, err)
+		}
+	}
+
+	if !db.DisableForeignKeyConstraintWhenMigrating && !db.IgnoreRelationshipsWhenMigrating {
+		for _, model := range models {
+			if table, ok := targets[model]; ok {
+				if err := migrateConstraints(scoped.Table(table), model, SchemaName(tenantID)); err != nil {
+					return fmt.Errorf("failed to migrate foreign keys of %s for tenant %s: %w", table, tenantID, err)
+				}
+			}
+		}
+	}
+
+	known.Store(tenantID, true)
+	return nil
+}
+
+// migrateConstraints adds the foreign keys AutoMigrate would create for model, pointing them at the
+// tenant's tables. Existing keys are matched on their columns and referenced table, not their name:
+// GORM names the key declared on the other side of a has-many differently depending on parse order.
+func migrateConstraints(db *gorm.DB, model interface{}, schemaName string) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	table := schemaName + "." + db.Statement.Table
+	wanted := map[string]*schema.Constraint{}
+	for _, rel := range stmt.Schema.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		constraint := rel.ParseConstraint()
+		if constraint == nil || constraint.Schema != stmt.Schema {
+			continue
+		}
+
+		key := constraintKey(constraint)
+		// The same key may be declared on both sides; keep the one with ON DELETE/ON UPDATE actions
+		if current, ok := wanted[key]; !ok || (current.OnDelete == "" && current.OnUpdate == "") {
+			wanted[key] = constraint
+		}
+	}
+
+	keys := make([]string, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+	for _, key := range keys {
+		constraint := wanted[key]
+		_, refTable, ok := strings.Cut(constraint.ReferenceSchema.Table, ".")
+		if !ok {
+			refTable = constraint.ReferenceSchema.Table
+		}
+		refTable = schemaName + "." + refTable
+
+		columns := make([]string, 0, len(constraint.ForeignKeys))
+		for _, field := range constraint.ForeignKeys {
+			columns = append(columns, field.DBName)
+		}
+
+		var count int64
+		err := tx.Raw(`SELECT count(*) FROM pg_constraint c
+			WHERE c.contype = 'f' AND c.conrelid = to_regclass(?) AND c.confrelid = to_regclass(?)
+			AND ARRAY(
+				SELECT a.attname::text FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			) = ?::text[]`,
+			db.Statement.Quote(table), db.Statement.Quote(refTable), columns).Scan(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		sql, vars := constraint.Build()
+		vars[2] = clause.Table{Name: refTable}
+		if err := tx.Exec("ALTER TABLE ? ADD "+sql, append([]interface{}{clause.Table{Name: table}}, vars...)...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func constraintKey(constraint *schema.Constraint) string {
+	_, refTable, ok := strings.Cut(constraint.ReferenceSchema.Table, ".")
+	if !ok {
+		refTable = constraint.ReferenceSchema.Table
+	}
+	parts := []string{refTable}
+	for _, field := range constraint.ForeignKeys {
+		parts = append(parts, field.DBName)
+	}
+	return strings.Join(parts, ",")
+}
+
+// MigrateAll runs Migrate for every tenant returned by List and stops at the first failure.
+func MigrateAll(ctx context.Context, db *gorm.DB, models ...interface{}) error {
+	ids, err := List(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := Migrate(db.WithContext(ctx), id, models...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
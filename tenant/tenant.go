@@ -0,0 +1,96 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+)
+
+// Each tenant gets its own copy of the shared-models tables. Models keep their `v1.` TableName;
+// the callbacks installed by Register swap BaseSchema for the tenant's schema at query time.
+var (
+	BaseSchema   = "v1"      // Schema hard-coded in the shared-models TableName methods
+	SchemaPrefix = "tenant_" // Tenant "acme" lives in schema "tenant_acme"
+)
+
+var (
+	ErrInvalidTenant = errors.New("invalid tenant id")
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,39}$`)
+
+// ValidateID checks that tenantID is usable as part of a schema name: lowercase letters, digits and
+// underscores, at most 40 characters.
+func ValidateID(tenantID string) error {
+	if !validID.MatchString(tenantID) {
+		return fmt.Errorf("%w: %q", ErrInvalidTenant, tenantID)
+	}
+	return nil
+}
+
+// SchemaName returns the schema holding the tables of tenantID.
+func SchemaName(tenantID string) string {
+	return SchemaPrefix + tenantID
+}
+
+// Scoped returns db bound to tenantID, for work outside an HTTP request such as jobs,
+// migrations and CSV imports. Requests get the same scoping from Middleware.
+func Scoped(db *gorm.DB, tenantID string) *gorm.DB {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return db.WithContext(utils.WithTenant(ctx, tenantID))
+}
+
+// known caches tenants whose schema has been seen, so Exists only hits the database once per tenant.
+var known sync.Map
+
+// Exists reports whether the schema of tenantID has been created.
+func Exists(ctx context.Context, db *gorm.DB, tenantID string) (bool, error) {
+	if err := ValidateID(tenantID); err != nil {
+		return false, err
+	}
+	if _, ok := known.Load(tenantID); ok {
+		return true, nil
+	}
+
+	var count int64
+	err := db.WithContext(ctx).
+		Raw("SELECT count(*) FROM information_schema.schemata WHERE schema_name = ?", SchemaName(tenantID)).
+		Scan(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		known.Store(tenantID, true)
+	}
+	return count > 0, nil
+}
+
+// List returns the ids of all tenants that have a schema.
+func List(ctx context.Context, db *gorm.DB) ([]string, error) {
+	var schemas []string
+	err := db.WithContext(ctx).
+		Raw("SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE ? ORDER BY schema_name", SchemaPrefix+"%").
+		Scan(&schemas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		id := strings.TrimPrefix(schema, SchemaPrefix)
+		if ValidateID(id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const actorKey contextKey = "actor"
+
+// WithActor returns a copy of ctx carrying the identity of the authenticated user.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the authenticated user stored by WithActor, or "" if there is none.
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// ActorFromClaims returns the user_id claim of a token issued by GenerateJWT as a string.
+func ActorFromClaims(claims jwt.MapClaims) string {
+	if userID, ok := claims["user_id"].(float64); ok {
+		return strconv.Itoa(int(userID))
+	}
+	return ""
+}
+
+const requestMetaKey contextKey = "request_meta"
+
+// RequestMeta identifies the HTTP request a database change originated from.
+type RequestMeta struct {
+	RequestID string
+	IP        string
+	UserAgent string
+}
+
+// WithRequestMeta returns a copy of ctx carrying meta.
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey, meta)
+}
+
+// RequestMetaFromContext returns the RequestMeta stored by WithRequestMeta, or an empty one.
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	if ctx == nil {
+		return RequestMeta{}
+	}
+	meta, _ := ctx.Value(requestMetaKey).(RequestMeta)
+	return meta
+}
+
+const tenantKey contextKey = "tenant"
+
+// WithTenant returns a copy of ctx scoped to tenantID. Queries run with db.WithContext(ctx)
+// are routed to the tenant's schema by the callbacks installed with tenant.Register.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant stored by WithTenant, or "" if there is none.
+func TenantFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenantID, _ := ctx.Value(tenantKey).(string)
+	return tenantID
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"log"
 	"math"
 
@@ -14,7 +15,10 @@ type PaginatedResult struct {
 	Records     interface{} `json:"records"`
 }
 
-// Paginate fetches records dynamically with optional filters & preloads
+// Paginate fetches records dynamically with optional filters & preloads.
+// Records are ordered by id descending unless db already has an Order.
+// The count, the page and the preloads all run with db's context, so a db bound to a tenant
+// (db.WithContext(c.Context()) behind tenant.Middleware) pages through that tenant's rows.
 func Paginate(db *gorm.DB, model interface{}, page, limit int, filters map[string]interface{}, preloads []string) (*PaginatedResult, error) {
 	var totalCount int64
 	offset := (page - 1) * limit
@@ -39,7 +43,10 @@ func Paginate(db *gorm.DB, model interface{}, page, limit int, filters map[strin
 	}
 
 	// Fetch paginated records
-	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(model).Error; err != nil {
+	if _, ordered := query.Statement.Clauses["ORDER BY"]; !ordered {
+		query = query.Order("id DESC")
+	}
+	if err := query.Limit(limit).Offset(offset).Find(model).Error; err != nil {
 		log.Printf("Error retrieving paginated records: %v", err)
 		return nil, err
 	}
@@ -53,3 +60,8 @@ func Paginate(db *gorm.DB, model interface{}, page, limit int, filters map[strin
 		Records:     model,
 	}, nil
 }
+
+// PaginateContext is Paginate with db bound to ctx, e.g. the request context carrying the tenant.
+func PaginateContext(ctx context.Context, db *gorm.DB, model interface{}, page, limit int, filters map[string]interface{}, preloads []string) (*PaginatedResult, error) {
+	return Paginate(db.WithContext(ctx), model, page, limit, filters, preloads)
+}
@@ -2,59 +2,68 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/DevdotSP/go-utils/fetchparam"
 	"gorm.io/gorm"
 )
 
 // GetParamValue is a helper function to fetch a single value from a parameter result.
+// Numbers, booleans, times and byte slices returned by the driver are converted to text.
 func GetParamValue(result map[string]interface{}, key string) (string, error) {
-	value, ok := result[key].(string)
-	if !ok {
-		return "", fmt.Errorf("%s not found or invalid type", key)
+	switch value := result[key].(type) {
+	case string:
+		return value, nil
+	case []byte:
+		return string(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case int32:
+		return strconv.FormatInt(int64(value), 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case time.Time:
+		return value.Format(time.RFC3339), nil
 	}
-	return value, nil
+	return "", fmt.Errorf("%s not found or invalid type", key)
 }
 
-//example of columnToSelect []string{"api"} 
+//example of columnToSelect []string{"api"}
 
 // GetGCSPATH fetches the 'api' value from Oasis parameters
+//
+// Deprecated: use the cached, typed sysparam.Service instead.
 func GetGCSPATH(db *gorm.DB, tableName, columnName, columnValue string, columnToSelect []string) (string, error) {
-	result, err := fetchparam.FetchParam(db, tableName,  columnName, columnValue, columnToSelect)
-	if err != nil {
-		return "", err
-	}
-
-	api, err := GetParamValue(result, "api")
-	if err != nil {
-		return "", err
-	}
-
-	return api, nil
+	return getAPI(db, tableName, columnName, columnValue, columnToSelect)
 }
 
-//example of columnToSelect []string{"api"} 
+//example of columnToSelect []string{"api"}
 
 // GetAllAPI fetches the 'api' value from the API table
+//
+// Deprecated: use the cached, typed sysparam.Service instead.
 func GetAllAPI(db *gorm.DB, tableName, columnName, columnValue string, columnToSelect []string) (string, error) {
-	result, err := fetchparam.FetchParam(db, tableName, columnName, columnValue, columnToSelect)
-	if err != nil {
-		return "", err
-	}
+	return getAPI(db, tableName, columnName, columnValue, columnToSelect)
+}
 
-	api, err := GetParamValue(result, "api")
+func getAPI(db *gorm.DB, tableName, columnName, columnValue string, columnToSelect []string) (string, error) {
+	result, err := fetchparam.FetchParam(db, tableName, columnName, columnValue, columnToSelect)
 	if err != nil {
 		return "", err
 	}
-
-	return api, nil
+	return GetParamValue(result, "api")
 }
 
-//example of columnToSelect []string{"key", "value"} 
+//example of columnToSelect []string{"key", "value"}
 
 // GetSystemParam fetches 'key' and 'value' from the system parameters table
+//
+// Deprecated: use sysparam.Service, whose String, Int, Bool, Duration and JSON getters are cached.
 func GetSystemParam(db *gorm.DB, tableName, columnName, columnValue string, columnToSelect []string) (string, string, error) {
-	result, err := fetchparam.FetchParam(db, tableName, columnName, columnValue, columnToSelect )
+	result, err := fetchparam.FetchParam(db, tableName, columnName, columnValue, columnToSelect)
 	if err != nil {
 		return "", "", err
 	}
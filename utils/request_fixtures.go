@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FixtureMode selects what Fixtures does with requests
+type FixtureMode string
+
+const (
+	FixtureRecord FixtureMode = "record" // Send requests and save every interaction
+	FixtureReplay FixtureMode = "replay" // Serve saved interactions without sending anything
+)
+
+// ErrFixtureNotFound is returned in replay mode for a request that was never recorded
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// Fixtures records ApiRequest interactions to JSON files and serves them back, so integration tests run
+// offline. A request matches a fixture by method, URL and body; multipart bodies are not compared because
+// of their random boundary. The n-th identical request gets the n-th recorded response, or the last one
+type Fixtures struct {
+	Dir      string
+	Mode     FixtureMode
+	Redactor *Redactor // Hides secrets of saved requests and responses. Defaults to DefaultRedactor
+
+	// RawResponses saves JSON and form response bodies as received instead of redacting their fields,
+	// for tests that need the real tokens on replay. The fixtures then hold secrets
+	RawResponses bool
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Fixture is one recorded interaction
+type Fixture struct {
+	Request  FixtureMessage `json:"request"`
+	Response FixtureMessage `json:"response"`
+}
+
+// FixtureMessage is the request or response of a Fixture
+type FixtureMessage struct {
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Encoding   string      `json:"encoding,omitempty"` // "base64" for a binary body
+}
+
+// NewFixtures creates Fixtures recording to or replaying from dir
+func NewFixtures(dir string, mode FixtureMode) *Fixtures {
+	return &Fixtures{Dir: dir, Mode: mode}
+}
+
+// FixturesFromEnv creates Fixtures from API_FIXTURE_MODE ("record" or "replay") and API_FIXTURE_DIR,
+// defaulting to testdata/fixtures. It returns nil when API_FIXTURE_MODE is not set
+func FixturesFromEnv() (*Fixtures, error) {
+	mode := FixtureMode(strings.ToLower(os.Getenv("API_FIXTURE_MODE")))
+	switch mode {
+	case "":
+		return nil, nil
+	case FixtureRecord, FixtureReplay:
+	default:
+		return nil, fmt.Errorf("unsupported API_FIXTURE_MODE: %s", mode)
+	}
+	dir := os.Getenv("API_FIXTURE_DIR")
+	if dir == "" {
+		dir = filepath.Join("testdata", "fixtures")
+	}
+	return NewFixtures(dir, mode), nil
+}
+
+// Middleware records or replays requests according to the mode of f
+func (f *Fixtures) Middleware(next RoundTrip) RoundTrip {
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		name, n := f.name(req, body)
+
+		if f.Mode == FixtureReplay {
+			return f.replay(req, name, n)
+		}
+
+		resp, err := next(req)
+		if err != nil {
+			return resp, err
+		}
+		if err := f.record(req, body, resp, name, n); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+func (f *Fixtures) replay(req *http.Request, name string, n int) (*http.Response, error) {
+	fixture, err := f.load(name, n)
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(fixture.Response.Body)
+	if fixture.Response.Encoding == "base64" {
+		if body, err = base64.StdEncoding.DecodeString(fixture.Response.Body); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+	}
+	header := fixture.Response.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.StatusCode, http.StatusText(fixture.Response.StatusCode)),
+		StatusCode:    fixture.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// load reads the n-th fixture of name, falling back to the last one recorded
+func (f *Fixtures) load(name string, n int) (*Fixture, error) {
+	for ; n >= 1; n-- {
+		data, err := os.ReadFile(f.path(name, n))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+		return &fixture, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrFixtureNotFound, f.path(name, 1))
+}
+
+func (f *Fixtures) record(req *http.Request, reqBody []byte, resp *http.Response, name string, n int) error {
+	respBody, err := readResponseBody(resp)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	redactor := f.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor
+	}
+	fixture := Fixture{
+		Request: FixtureMessage{
+			Method: req.Method,
+			URL:    redactor.URL(req.URL.String()),
+			Header: redactor.Header(req.Header),
+		},
+		Response: FixtureMessage{
+			StatusCode: resp.StatusCode,
+			Header:     redactor.Header(resp.Header),
+		},
+	}
+	fixture.Request.Body, fixture.Request.Encoding = encodeBody(redactor.Body(reqBody, req.Header.Get("Content-Type")))
+	if !f.RawResponses {
+		respBody = redactor.Body(respBody, resp.Header.Get("Content-Type"))
+	}
+	fixture.Response.Body, fixture.Response.Encoding = encodeBody(respBody)
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(fixture); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(f.path(name, n), data.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// name returns the file name of the interaction and how many times it was seen, this time included
+func (f *Fixtures) name(req *http.Request, body []byte) (string, int) {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.String() + "\n"))
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/") {
+		hash.Write(body)
+	}
+
+	readable := unsafeFixtureChars.ReplaceAllString(req.URL.Host+req.URL.Path, "_")
+	if len(readable) > 80 {
+		readable = readable[:80]
+	}
+	name := fmt.Sprintf("%s_%s_%s", strings.ToLower(req.Method), strings.Trim(readable, "_"), hex.EncodeToString(hash.Sum(nil))[:12])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[name]++
+	return name, f.calls[name]
+}
+
+func (f *Fixtures) path(name string, n int) string {
+	if n > 1 {
+		name = fmt.Sprintf("%s.%d", name, n)
+	}
+	return filepath.Join(f.Dir, name+".json")
+}
+
+// Reset restarts the count of identical requests, e.g. between tests
+func (f *Fixtures) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RoundTrip sends one attempt of an ApiRequest
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps every attempt of an ApiRequest. It may inspect or change the request, call next,
+// and inspect or replace the response. A middleware reading a body must put an unread copy back
+type Middleware func(next RoundTrip) RoundTrip
+
+// Middlewares run around every ApiRequest, before the ones added with ApiRequest.Use. Set them at startup
+var Middlewares []Middleware
+
+// UseMiddleware adds middlewares run around every ApiRequest
+func UseMiddleware(mw ...Middleware) {
+	Middlewares = append(Middlewares, mw...)
+}
+
+// chain wraps send with the global and request middlewares, the first one being the outermost
+func chain(send RoundTrip, local []Middleware) RoundTrip {
+	all := append(append([]Middleware{}, Middlewares...), local...)
+	for i := len(all) - 1; i >= 0; i-- {
+		send = all[i](send)
+	}
+	return send
+}
+
+// Redacted replaces secrets in logs and fixtures
+const Redacted = "[REDACTED]"
+
+// Redactor hides secrets in headers, query parameters and JSON or form bodies. Names are case-insensitive
+type Redactor struct {
+	Headers []string
+	Fields  []string // Query parameters and body fields, at any depth of a JSON body
+}
+
+// DefaultRedactor hides credentials, cookies, passwords and tokens
+var DefaultRedactor = &Redactor{
+	Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Idempotency-Key"},
+	Fields: []string{
+		"password", "new_password", "old_password", "confirm_password", "secret", "client_secret",
+		"token", "access_token", "refresh_token", "id_token", "api_key", "apikey", "signature",
+	},
+}
+
+// Header returns a copy of header with the redacted values replaced
+func (rd *Redactor) Header(header http.Header) http.Header {
+	redacted := header.Clone()
+	if rd == nil {
+		return redacted
+	}
+	for _, name := range rd.Headers {
+		if len(redacted.Values(name)) > 0 {
+			redacted.Set(name, Redacted)
+		}
+	}
+	return redacted
+}
+
+// URL returns rawURL with the redacted query parameters replaced
+func (rd *Redactor) URL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.RawQuery == "" || rd == nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	for key := range query {
+		if rd.field(key) {
+			query.Set(key, Redacted)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// Body returns body with the redacted fields replaced. JSON and form bodies are redacted field by field,
+// other bodies are returned as is
+func (rd *Redactor) Body(body []byte, contentType string) []byte {
+	if rd == nil || len(body) == 0 {
+		return body
+	}
+	switch {
+	case strings.Contains(contentType, "json"):
+		var value interface{}
+		if err := json.Unmarshal(body, &value); err != nil {
+			return body
+		}
+		redacted, err := json.Marshal(rd.value(value))
+		if err != nil {
+			return body
+		}
+		return redacted
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+		for key := range values {
+			if rd.field(key) {
+				values.Set(key, Redacted)
+			}
+		}
+		return []byte(values.Encode())
+	}
+	return body
+}
+
+func (rd *Redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if rd.field(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = rd.value(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rd.value(item)
+		}
+	}
+	return value
+}
+
+func (rd *Redactor) field(name string) bool {
+	for _, field := range rd.Fields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogOptions configures LogRequests
+type LogOptions struct {
+	Logger   *slog.Logger // Defaults to slog.Default()
+	Redactor *Redactor    // Defaults to DefaultRedactor
+	Headers  bool         // Log request and response headers
+	Bodies   bool         // Log request and response bodies, up to MaxBody bytes
+	MaxBody  int          // Defaults to 2048
+}
+
+// LogRequests logs the method, URL, status and latency of every attempt, and the error of a failed one.
+// Responses from 500 and failed attempts are logged as errors, responses from 400 as warnings
+func LogRequests(opts LogOptions) Middleware {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Redactor == nil {
+		opts.Redactor = DefaultRedactor
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = 2048
+	}
+
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", opts.Redactor.URL(req.URL.String())),
+			}
+			if meta := RequestMetaFromContext(req.Context()); meta.RequestID != "" {
+				attrs = append(attrs, slog.String("request_id", meta.RequestID))
+			}
+			if opts.Headers {
+				attrs = append(attrs, slog.Any("request_headers", opts.Redactor.Header(req.Header)))
+			}
+			if opts.Bodies {
+				body, err := readRequestBody(req)
+				if err != nil {
+					return nil, err
+				}
+				attrs = append(attrs, slog.String("request_body", opts.truncate(opts.Redactor.Body(body, req.Header.Get("Content-Type")))))
+			}
+
+			start := time.Now()
+			resp, err := next(req)
+			attrs = append(attrs, slog.Duration("latency", time.Since(start)))
+
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				opts.Logger.LogAttrs(req.Context(), slog.LevelError, "api request failed", attrs...)
+				return resp, err
+			}
+
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			if opts.Headers {
+				attrs = append(attrs, slog.Any("response_headers", opts.Redactor.Header(resp.Header)))
+			}
+			if opts.Bodies {
+				body, err := readResponseBody(resp)
+				if err != nil {
+					return nil, err
+				}
+				attrs = append(attrs, slog.String("response_body", opts.truncate(opts.Redactor.Body(body, resp.Header.Get("Content-Type")))))
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case resp.StatusCode >= 500:
+				level = slog.LevelError
+			case resp.StatusCode >= 400:
+				level = slog.LevelWarn
+			}
+			opts.Logger.LogAttrs(req.Context(), level, "api request", attrs...)
+			return resp, nil
+		}
+	}
+}
+
+func (opts LogOptions) truncate(body []byte) string {
+	if len(body) > opts.MaxBody {
+		return string(body[:opts.MaxBody]) + "…"
+	}
+	return string(body)
+}
+
+// readRequestBody reads the body of req and puts an unread copy back
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+// readResponseBody reads the body of resp and puts an unread copy back
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,420 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Errors of calls rejected before reaching the host
+var (
+	ErrCircuitOpen  = errors.New("circuit breaker is open")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+	ErrBulkheadFull = errors.New("too many concurrent requests")
+)
+
+// ResilienceError is returned for a call rejected by the policy of its host. It wraps ErrCircuitOpen,
+// ErrRateLimited or ErrBulkheadFull
+type ResilienceError struct {
+	Host       string
+	Err        error
+	RetryAfter time.Duration // When the call may succeed, if known
+}
+
+func (e *ResilienceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Host, e.Err)
+}
+
+func (e *ResilienceError) Unwrap() error {
+	return e.Err
+}
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// HostPolicy limits the calls to one host. A zero value disables the corresponding limit
+type HostPolicy struct {
+	FailureThreshold int           `json:"failure_threshold"` // Consecutive failures opening the circuit
+	OpenTimeout      time.Duration `json:"-"`                 // Time the circuit stays open before letting probes through
+	HalfOpenRequests int           `json:"half_open_requests"`
+	RatePerSecond    float64       `json:"rate_per_second"` // Token bucket refill rate
+	Burst            int           `json:"burst"`
+	MaxConcurrent    int           `json:"max_concurrent"`
+	MaxWait          time.Duration `json:"-"` // Wait for a token or a concurrency slot before failing
+}
+
+// DefaultHostPolicy opens the circuit of a host after 5 consecutive failures for 30 seconds and allows
+// 64 concurrent calls per host, waiting up to a second for a slot
+var DefaultHostPolicy = HostPolicy{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+	HalfOpenRequests: 1,
+	MaxConcurrent:    64,
+	MaxWait:          time.Second,
+}
+
+// HostStats is a snapshot of the state of one host
+type HostStats struct {
+	Host             string    `json:"host"`
+	State            string    `json:"state"`
+	Failures         int       `json:"consecutive_failures"`
+	InFlight         int       `json:"in_flight"`
+	Tokens           float64   `json:"tokens,omitempty"`
+	Successes        int64     `json:"successes"`
+	Errors           int64     `json:"errors"`
+	RejectedOpen     int64     `json:"rejected_circuit_open"`
+	RejectedRate     int64     `json:"rejected_rate_limited"`
+	RejectedBulkhead int64     `json:"rejected_bulkhead_full"`
+	OpenedAt         time.Time `json:"opened_at,omitempty"`
+}
+
+// Resilience holds the circuit breaker, token bucket and concurrency limit of every host called through it
+type Resilience struct {
+	Default HostPolicy
+
+	mu       sync.Mutex
+	policies map[string]HostPolicy
+	hosts    map[string]*hostState
+}
+
+// DefaultResilience guards every ApiRequest unless Resilience is called
+var DefaultResilience = NewResilience(DefaultHostPolicy)
+
+// NewResilience returns a Resilience applying policy to hosts without their own
+func NewResilience(policy HostPolicy) *Resilience {
+	return &Resilience{Default: policy, policies: map[string]HostPolicy{}, hosts: map[string]*hostState{}}
+}
+
+// SetPolicy sets the policy of host, e.g. "api.example.com" or "api.example.com:8443", resetting its state
+func (r *Resilience) SetPolicy(host string, policy HostPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	host = strings.ToLower(host)
+	r.policies[host] = policy
+	delete(r.hosts, host)
+}
+
+// LoadPolicies reads host policies from a key/value parameter table, such as v1.system_parameter: every row whose key is
+// "resilience.<host>" holds the JSON HostPolicy of host, with durations as strings, e.g.
+// {"failure_threshold": 3, "open_timeout": "1m", "rate_per_second": 10, "burst": 20, "max_concurrent": 8, "max_wait": "500ms"}
+func (r *Resilience) LoadPolicies(db *gorm.DB, tableName string) error {
+	var rows []struct {
+		Key   string
+		Value string
+	}
+	if err := db.Table(tableName).Select("key", "value").Where("key LIKE ?", "resilience.%").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load resilience policies: %w", err)
+	}
+
+	for _, row := range rows {
+		policy, err := ParseHostPolicy(row.Value, r.Default)
+		if err != nil {
+			return fmt.Errorf("invalid policy %s: %w", row.Key, err)
+		}
+		r.SetPolicy(strings.TrimPrefix(row.Key, "resilience."), policy)
+	}
+	return nil
+}
+
+// ParseHostPolicy parses a JSON HostPolicy, keeping the fields of base that it does not set
+func ParseHostPolicy(value string, base HostPolicy) (HostPolicy, error) {
+	policy := struct {
+		*HostPolicy
+		OpenTimeout string `json:"open_timeout"`
+		MaxWait     string `json:"max_wait"`
+	}{HostPolicy: &base}
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return base, err
+	}
+
+	var err error
+	if policy.OpenTimeout != "" {
+		if base.OpenTimeout, err = time.ParseDuration(policy.OpenTimeout); err != nil {
+			return base, err
+		}
+	}
+	if policy.MaxWait != "" {
+		if base.MaxWait, err = time.ParseDuration(policy.MaxWait); err != nil {
+			return base, err
+		}
+	}
+	return base, nil
+}
+
+// Stats returns the state of every host called so far, sorted by host
+func (r *Resilience) Stats() []HostStats {
+	r.mu.Lock()
+	hosts := make([]*hostState, 0, len(r.hosts))
+	for _, h := range r.hosts {
+		hosts = append(hosts, h)
+	}
+	r.mu.Unlock()
+
+	stats := make([]HostStats, 0, len(hosts))
+	for _, h := range hosts {
+		stats = append(stats, h.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+// host returns the state of the host of rawURL, or nil when r is nil
+func (r *Resilience) host(rawURL string) *hostState {
+	if r == nil {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	host := strings.ToLower(parsed.Host)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.hosts[host]; ok {
+		return h
+	}
+	policy, ok := r.policies[host]
+	if !ok {
+		policy, ok = r.policies[parsed.Hostname()]
+	}
+	if !ok {
+		policy = r.Default
+	}
+	h := newHostState(host, policy)
+	r.hosts[host] = h
+	return h
+}
+
+// hostState is the circuit breaker, token bucket and bulkhead of one host
+type hostState struct {
+	host   string
+	policy HostPolicy
+	slots  chan struct{}
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probes   int
+	tokens   float64
+	refilled time.Time
+
+	successes, failed                        int64
+	rejectedOpen, rejectedRate, rejectedFull int64
+}
+
+func newHostState(host string, policy HostPolicy) *hostState {
+	h := &hostState{host: host, policy: policy, state: CircuitClosed, tokens: float64(max(policy.Burst, 1)), refilled: time.Now()}
+	if policy.MaxConcurrent > 0 {
+		h.slots = make(chan struct{}, policy.MaxConcurrent)
+	}
+	return h
+}
+
+// Outcomes of a call, see hostState.acquire
+const (
+	callSucceeded = iota
+	callFailed
+	callIgnored // Cancelled by the caller: says nothing about the host
+)
+
+// acquire admits one call, waiting for a token or a slot up to MaxWait. The returned function
+// must be called with the outcome of the call
+func (h *hostState) acquire(ctx context.Context) (func(outcome int), error) {
+	halfOpen, err := h.allow()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.take(ctx); err != nil {
+		h.cancelProbe(halfOpen)
+		return nil, err
+	}
+
+	if h.slots != nil {
+		timer := time.NewTimer(h.policy.MaxWait)
+		defer timer.Stop()
+		select {
+		case h.slots <- struct{}{}:
+		case <-timer.C:
+			h.cancelProbe(halfOpen)
+			h.count(&h.rejectedFull)
+			return nil, &ResilienceError{Host: h.host, Err: ErrBulkheadFull}
+		case <-ctx.Done():
+			h.cancelProbe(halfOpen)
+			return nil, ctx.Err()
+		}
+	}
+
+	return func(outcome int) {
+		if h.slots != nil {
+			<-h.slots
+		}
+		if outcome == callIgnored {
+			h.cancelProbe(halfOpen)
+			return
+		}
+		h.record(outcome == callSucceeded)
+	}, nil
+}
+
+// allow applies the circuit breaker, reporting whether the call is a half-open probe
+func (h *hostState) allow() (bool, error) {
+	if h.policy.FailureThreshold <= 0 {
+		return false, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state == CircuitOpen {
+		if wait := h.policy.OpenTimeout - time.Since(h.openedAt); wait > 0 {
+			h.rejectedOpen++
+			return false, &ResilienceError{Host: h.host, Err: ErrCircuitOpen, RetryAfter: wait}
+		}
+		h.transition(CircuitHalfOpen)
+	}
+	if h.state == CircuitHalfOpen {
+		if h.probes >= max(h.policy.HalfOpenRequests, 1) {
+			h.rejectedOpen++
+			return false, &ResilienceError{Host: h.host, Err: ErrCircuitOpen}
+		}
+		h.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (h *hostState) cancelProbe(halfOpen bool) {
+	if !halfOpen {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state == CircuitHalfOpen && h.probes > 0 {
+		h.probes--
+	}
+}
+
+// take removes a token from the bucket, waiting for one up to MaxWait
+func (h *hostState) take(ctx context.Context) error {
+	if h.policy.RatePerSecond <= 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	now := time.Now()
+	burst := float64(max(h.policy.Burst, 1))
+	h.tokens = min(burst, h.tokens+now.Sub(h.refilled).Seconds()*h.policy.RatePerSecond)
+	h.refilled = now
+
+	wait := time.Duration(0)
+	if h.tokens < 1 {
+		wait = time.Duration((1 - h.tokens) / h.policy.RatePerSecond * float64(time.Second))
+		if wait > h.policy.MaxWait {
+			h.rejectedRate++
+			h.mu.Unlock()
+			return &ResilienceError{Host: h.host, Err: ErrRateLimited, RetryAfter: wait}
+		}
+	}
+	// Reserve the token now so concurrent callers queue behind each other
+	h.tokens--
+	h.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record updates the circuit breaker with the outcome of a call
+func (h *hostState) record(success bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if success {
+		h.successes++
+		h.failures = 0
+		if h.state == CircuitHalfOpen {
+			h.transition(CircuitClosed)
+		}
+		return
+	}
+
+	h.failed++
+	h.failures++
+	if h.policy.FailureThreshold <= 0 {
+		return
+	}
+	if h.state == CircuitHalfOpen || (h.state == CircuitClosed && h.failures >= h.policy.FailureThreshold) {
+		h.transition(CircuitOpen)
+	}
+}
+
+func (h *hostState) transition(state string) {
+	if h.state == state {
+		return
+	}
+	h.state = state
+	h.probes = 0
+	switch state {
+	case CircuitOpen:
+		h.openedAt = time.Now()
+		log.Printf("⚠️ Circuit breaker for %s opened after %d consecutive failures", h.host, h.failures)
+	case CircuitClosed:
+		log.Printf("✅ Circuit breaker for %s closed", h.host)
+	}
+}
+
+func (h *hostState) count(counter *int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*counter++
+}
+
+func (h *hostState) stats() HostStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := HostStats{
+		Host:             h.host,
+		State:            h.state,
+		Failures:         h.failures,
+		InFlight:         len(h.slots),
+		Successes:        h.successes,
+		Errors:           h.failed,
+		RejectedOpen:     h.rejectedOpen,
+		RejectedRate:     h.rejectedRate,
+		RejectedBulkhead: h.rejectedFull,
+	}
+	if h.state != CircuitClosed {
+		stats.OpenedAt = h.openedAt
+	}
+	if h.policy.RatePerSecond > 0 {
+		stats.Tokens = h.tokens
+	}
+	// An expired open circuit only moves to half-open on the next call
+	if h.state == CircuitOpen && time.Since(h.openedAt) >= h.policy.OpenTimeout {
+		stats.State = CircuitHalfOpen
+	}
+	return stats
+}
@@ -2,32 +2,146 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// HTTPClient is shared by every ApiRequest unless Client is called, so connections to the same host are reused
+var HTTPClient = &http.Client{
+	Timeout:   30 * time.Second,
+	Transport: newTransport(),
+}
+
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 20
+	transport.IdleConnTimeout = 90 * time.Second
+	return transport
+}
+
+// RetryPolicy retries requests failing with a network error or one of the RetryOn status codes. The delay
+// doubles after every attempt up to MaxBackoff, with jitter, unless the response has a Retry-After header
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	RetryOn    []int
+}
+
+// DefaultRetryPolicy tries 3 times on network errors, 429 and 502 to 504
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    200 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+	RetryOn:    []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+// NoRetry sends requests once
+var NoRetry = RetryPolicy{Attempts: 1}
+
+// FormFile is a file of a multipart body
+type FormFile struct {
+	Field       string
+	Filename    string
+	ContentType string // Defaults to application/octet-stream
+	Data        []byte
+}
+
+// HTTPError is returned for a response outside 2xx
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// UpstreamError is returned when the upstream of an ApiRequest cannot be reached or its response cannot
+// be read, e.g. a refused connection or an attempt timeout. Cancellation of the caller's context is not one
+type UpstreamError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Response is a complete response of an ApiRequest
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Attempts   int
+}
+
+// JSON decodes the body into v. An empty body leaves v unchanged
+func (r *Response) JSON(v interface{}) error {
+	if len(bytes.TrimSpace(r.Body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	return nil
+}
+
 // ApiRequest struct stores request details
 type ApiRequest struct {
-	method  string
-	url     string
-	headers map[string]string
-	payload map[string]interface{}
+	method      string
+	url         string
+	headers     map[string]string
+	query       url.Values
+	body        func() (io.Reader, string, error) // Called once per attempt so retries resend the whole body
+	contentType string
+	ctx         context.Context
+	timeout     time.Duration
+	client      *http.Client
+	retry       RetryPolicy
+	retryUnsafe bool
+	resilience  *Resilience
+	middlewares []Middleware
+	err         error
 }
 
 // NewApiRequest initializes a new request with default values
 func NewApiRequest() *ApiRequest {
 	return &ApiRequest{
-		method:  "GET",
-		headers: map[string]string{"Content-Type": "application/json"},
-		payload: make(map[string]interface{}),
+		method:      "GET",
+		headers:     map[string]string{},
+		query:       url.Values{},
+		contentType: "application/json",
+		ctx:         context.Background(),
+		client:      HTTPClient,
+		retry:       DefaultRetryPolicy,
+		resilience:  DefaultResilience,
 	}
 }
 
 // Method sets the HTTP method
 func (r *ApiRequest) Method(method string) *ApiRequest {
-	r.method = method
+	r.method = strings.ToUpper(method)
 	return r
 }
 
@@ -37,18 +151,73 @@ func (r *ApiRequest) URL(url string) *ApiRequest {
 	return r
 }
 
-// Payload sets the request body, supporting both maps and structs
+// Query adds a query parameter to the URL
+func (r *ApiRequest) Query(key, value string) *ApiRequest {
+	r.query.Add(key, value)
+	return r
+}
+
+// QueryParams adds query parameters to the URL
+func (r *ApiRequest) QueryParams(params map[string]string) *ApiRequest {
+	for key, value := range params {
+		r.query.Add(key, value)
+	}
+	return r
+}
+
+// Payload sets a JSON request body: a map, a struct, a slice or any other JSON value
 func (r *ApiRequest) Payload(data interface{}) *ApiRequest {
-	switch v := data.(type) {
-	case map[string]interface{}:
-		r.payload = v
-	default:
-		jsonData, err := json.Marshal(v)
-		if err == nil {
-			json.Unmarshal(jsonData, &r.payload)
-		} else {
-			fmt.Println("Invalid payload format:", err)
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		r.err = fmt.Errorf("failed to marshal payload: %w", err)
+		return r
+	}
+	return r.Raw(jsonData, "application/json")
+}
+
+// Raw sets the request body as is
+func (r *ApiRequest) Raw(body []byte, contentType string) *ApiRequest {
+	r.body = func() (io.Reader, string, error) {
+		return bytes.NewReader(body), contentType, nil
+	}
+	return r
+}
+
+// Form sets a form-urlencoded request body
+func (r *ApiRequest) Form(values url.Values) *ApiRequest {
+	return r.Raw([]byte(values.Encode()), "application/x-www-form-urlencoded")
+}
+
+// Multipart sets a multipart/form-data request body with fields and files
+func (r *ApiRequest) Multipart(fields map[string]string, files ...FormFile) *ApiRequest {
+	r.body = func() (io.Reader, string, error) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for key, value := range fields {
+			if err := w.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+		for _, file := range files {
+			contentType := file.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(file.Field), escapeQuotes(file.Filename)))
+			header.Set("Content-Type", contentType)
+			part, err := w.CreatePart(header)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := part.Write(file.Data); err != nil {
+				return nil, "", err
+			}
 		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		return &buf, w.FormDataContentType(), nil
 	}
 	return r
 }
@@ -67,54 +236,264 @@ func (r *ApiRequest) Token(token string) *ApiRequest {
 	return r
 }
 
-// Send executes the HTTP request and returns the response as a map
+// BasicAuth adds an Authorization Basic header
+func (r *ApiRequest) BasicAuth(username, password string) *ApiRequest {
+	req := http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+	r.headers["Authorization"] = req.Header.Get("Authorization")
+	return r
+}
+
+// Context sets the context of the request, cancelling it with ctx
+func (r *ApiRequest) Context(ctx context.Context) *ApiRequest {
+	r.ctx = ctx
+	return r
+}
+
+// Timeout limits each attempt to d, instead of the Timeout of the client
+func (r *ApiRequest) Timeout(d time.Duration) *ApiRequest {
+	r.timeout = d
+	return r
+}
+
+// Client sends the request with client instead of HTTPClient
+func (r *ApiRequest) Client(client *http.Client) *ApiRequest {
+	r.client = client
+	return r
+}
+
+// Retry sets the retry policy. Only idempotent methods are retried, see IdempotencyKey
+func (r *ApiRequest) Retry(policy RetryPolicy) *ApiRequest {
+	r.retry = policy
+	return r
+}
+
+// Resilience guards the request with the circuit breaker and limits of its host in res instead of
+// DefaultResilience. A nil res sends it unguarded
+func (r *ApiRequest) Resilience(res *Resilience) *ApiRequest {
+	r.resilience = res
+	return r
+}
+
+// Use adds middlewares run around every attempt of the request, after the global Middlewares
+func (r *ApiRequest) Use(mw ...Middleware) *ApiRequest {
+	r.middlewares = append(r.middlewares, mw...)
+	return r
+}
+
+// IdempotencyKey sets the Idempotency-Key header, which makes a POST or PATCH safe to retry
+func (r *ApiRequest) IdempotencyKey(key string) *ApiRequest {
+	r.headers["Idempotency-Key"] = key
+	r.retryUnsafe = true
+	return r
+}
+
+// Send executes the HTTP request and returns the JSON object response as a map. An empty response
+// gives an empty map; use SendInto for other JSON values. Responses outside 2xx return an *HTTPError
+// along with the parsed body, if any
 func (r *ApiRequest) Send() (map[string]interface{}, error) {
-	client := &http.Client{}
-	var reqBody io.Reader
+	resp, err := r.Do()
+	if resp == nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	if parseErr := resp.JSON(&result); parseErr != nil && err == nil {
+		return nil, parseErr
+	}
+	return result, err
+}
+
+// SendInto executes the request and decodes the JSON response into a T
+func SendInto[T any](r *ApiRequest) (T, error) {
+	var result T
+	resp, err := r.Do()
+	if err != nil {
+		return result, err
+	}
+	return result, resp.JSON(&result)
+}
+
+// Do executes the request, retrying it according to its policy, and returns the complete response.
+// The response is also returned with an *HTTPError
+func (r *ApiRequest) Do() (*Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	attempts := max(r.retry.Attempts, 1)
+	if !r.idempotent() {
+		attempts = 1
+	}
+
+	backoff := r.retry.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := r.attempt()
+		if resp != nil {
+			resp.Attempts = attempt
+		}
+		if attempt >= attempts || !r.retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := jitter(backoff)
+		if after, ok := retryAfter(resp); ok {
+			delay = after
+		}
+		if r.retry.MaxBackoff > 0 {
+			delay = min(delay, r.retry.MaxBackoff)
+		}
+		select {
+		case <-r.ctx.Done():
+			return resp, fmt.Errorf("request failed: %w", r.ctx.Err())
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends the request once
+func (r *ApiRequest) attempt() (*Response, error) {
+	ctx := r.ctx
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
 
-	// Convert payload to JSON
-	if len(r.payload) > 0 {
-		jsonData, err := json.Marshal(r.payload)
+	req, err := r.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := callFailed
+	if host := r.resilience.host(req.URL.String()); host != nil {
+		release, err := host.acquire(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal payload: %w", err)
+			return nil, err
+		}
+		defer func() { release(outcome) }()
+	}
+
+	// Execute request
+	httpResp, err := chain(r.client.Do, r.middlewares)(req)
+	if err != nil {
+		if r.ctx.Err() != nil {
+			outcome = callIgnored
+		}
+		return nil, r.upstreamError(req, fmt.Errorf("request failed: %w", err))
+	}
+	defer httpResp.Body.Close()
+
+	// Read response
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, r.upstreamError(req, fmt.Errorf("failed to read response body: %w", err))
+	}
+	// Only server errors count against the host; a 4xx is the caller's problem
+	if httpResp.StatusCode < 500 {
+		outcome = callSucceeded
+	}
+
+	resp := &Response{StatusCode: httpResp.StatusCode, Header: httpResp.Header, Body: body}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return resp, &HTTPError{Method: r.method, URL: req.URL.String(), StatusCode: httpResp.StatusCode, Body: body}
+	}
+	return resp, nil
+}
+
+// upstreamError wraps err in an *UpstreamError, unless the caller's context is done
+func (r *ApiRequest) upstreamError(req *http.Request, err error) error {
+	if r.ctx.Err() != nil {
+		return err
+	}
+	return &UpstreamError{Method: r.method, URL: req.URL.String(), Err: err}
+}
+
+// build creates the HTTP request of one attempt
+func (r *ApiRequest) build(ctx context.Context) (*http.Request, error) {
+	target, err := url.Parse(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if len(r.query) > 0 {
+		query := target.Query()
+		for key, values := range r.query {
+			for _, value := range values {
+				query.Add(key, value)
+			}
+		}
+		target.RawQuery = query.Encode()
+	}
+
+	var reqBody io.Reader
+	contentType := r.contentType
+	if r.body != nil {
+		if reqBody, contentType, err = r.body(); err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonData)
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequest(r.method, r.url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, r.method, target.String(), reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
+	req.Header.Set("Content-Type", contentType)
 	for key, value := range r.headers {
 		req.Header.Set(key, value)
 	}
+	return req, nil
+}
 
-	// Execute request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+func (r *ApiRequest) idempotent() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
 	}
-	defer resp.Body.Close()
+	return r.retryUnsafe
+}
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// retryable reports whether an attempt failed in a way another attempt may not
+func (r *ApiRequest) retryable(resp *Response, err error) bool {
+	if err == nil || r.ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		for _, code := range r.retry.RetryOn {
+			if code == httpErr.StatusCode {
+				return true
+			}
+		}
+		return false
 	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
 
-	// Parse JSON response
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+// retryAfter returns the delay of a Retry-After header in seconds
+func retryAfter(resp *Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0, false
 	}
+	return time.Duration(seconds) * time.Second, true
+}
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// jitter returns a random delay between d/2 and d
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
 	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
 
-	return result, nil
+func escapeQuotes(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
 }
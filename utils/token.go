@@ -29,11 +29,26 @@ var activeTokens = sync.Map{}
 // Load secret key from environment variable
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 
-// GenerateJWT creates a JWT token and removes the old one if provided
-func GenerateJWT(userID int, currentToken string) (string, error) {
+// Optional claims of a token
+const (
+	ClaimTenant = "tenant_id" // The tenant the token was issued for
+	ClaimRoles  = "roles"     // The roles of the user, e.g. for the room restrictions of the WebSocket hub
+)
+
+// TokenClaims are the optional claims of a token
+type TokenClaims struct {
+	TenantID string   // Issued as ClaimTenant; tenant.Middleware only lets the token use this tenant
+	Roles    []string // Issued as ClaimRoles
+}
+
+// GenerateJWT creates a JWT token and removes the old one if provided. Optional claims bind the
+// token to a tenant and grant it roles.
+func GenerateJWT(userID int, currentToken string, extra ...TokenClaims) (string, error) {
 	// Remove old token if exists
 	if currentToken != "" {
-		activeTokens.Delete(currentToken)
+		if _, loaded := activeTokens.LoadAndDelete(currentToken); loaded {
+			notifyRevoked(currentToken)
+		}
 	}
 
 	// Define claims
@@ -43,6 +58,14 @@ func GenerateJWT(userID int, currentToken string) (string, error) {
 		"exp":     expirationTime.Unix(),
 		"iat":     time.Now().Unix(),
 	}
+	for _, e := range extra {
+		if e.TenantID != "" {
+			claims[ClaimTenant] = e.TenantID
+		}
+		if len(e.Roles) > 0 {
+			claims[ClaimRoles] = e.Roles
+		}
+	}
 
 	// Create token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -113,9 +136,31 @@ func DeleteToken(token string) error {
 		return ErrTokenNotFound
 	}
 	log.Printf("Token %s has been removed.", token)
+	notifyRevoked(token)
 	return nil
 }
 
+var (
+	revokeHooks   []func(token string)
+	revokeHooksMu sync.RWMutex
+)
+
+// OnTokenRevoked registers fn to be called when a token is removed by DeleteToken or replaced by GenerateJWT,
+// e.g. to close the connections it authenticated. fn must not block.
+func OnTokenRevoked(fn func(token string)) {
+	revokeHooksMu.Lock()
+	defer revokeHooksMu.Unlock()
+	revokeHooks = append(revokeHooks, fn)
+}
+
+func notifyRevoked(token string) {
+	revokeHooksMu.RLock()
+	defer revokeHooksMu.RUnlock()
+	for _, fn := range revokeHooks {
+		fn(token)
+	}
+}
+
 // CleanupExpiredTokens periodically removes expired tokens
 func CleanupExpiredTokens(ctx context.Context) {
 	for {
@@ -3,7 +3,9 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"math/big"
 	"os"
@@ -56,17 +58,20 @@ func GenerateTokenExpiry() time.Time {
 }
 
 // LoadEnv loads environment variables from a .env file.
-// It logs an error and stops execution if it can't load the .env file.
+// A missing .env file is not an error, as in containers the variables are injected into the
+// environment; variables already set are never overridden. See config.Loader for typed settings.
 func LoadEnv() error {
 	// Load the environment variables from the .env file
 	err := godotenv.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Println("⚠️ No .env file found, using the environment")
+		return nil
+	}
 	if err != nil {
-		log.Fatalf("Error loading .env file")
-		return fmt.Errorf("Error loading .env file")
+		log.Printf("❌ Error loading .env file: %v", err)
+		return fmt.Errorf("error loading .env file: %w", err)
 	}
 	log.Println("Environment variables loaded successfully")
-
-	// Optionally, check if any required variables are missing
 	return nil
 }
 
@@ -0,0 +1,232 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DevdotSP/go-utils/queue"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidURL       = errors.New("webhook URL must be an absolute http or https URL")
+	ErrNoEvents         = errors.New("webhook subscription needs at least one event type")
+	ErrNotRedeliverable = errors.New("webhook delivery is still queued")
+)
+
+// SubscriptionRequest creates or updates a subscription. Active is left unchanged when nil; enabling a
+// disabled subscription resets its failure count and resumes its pending deliveries.
+type SubscriptionRequest struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+func (req *SubscriptionRequest) validate() error {
+	parsed, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidURL
+	}
+	req.URL = parsed.String()
+
+	events := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return ErrNoEvents
+	}
+	req.Events = events
+	return nil
+}
+
+// CreateSubscription adds a subscription with a new secret, found in its Secret field.
+func (d *Dispatcher) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*sharedModels.WebhookSubscription, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if err := d.checkHost(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	secret, err := NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &sharedModels.WebhookSubscription{
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      datatypes.NewJSONSlice(req.Events),
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if !sub.Active {
+		now := time.Now()
+		sub.DisabledAt, sub.DisabledReason = &now, "disabled manually"
+	}
+	if err := d.DB.WithContext(ctx).Create(sub).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription, oldest first.
+func (d *Dispatcher) ListSubscriptions(ctx context.Context) ([]sharedModels.WebhookSubscription, error) {
+	subs := []sharedModels.WebhookSubscription{}
+	if err := d.DB.WithContext(ctx).Order("id").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// GetSubscription returns a subscription.
+func (d *Dispatcher) GetSubscription(ctx context.Context, id int64) (*sharedModels.WebhookSubscription, error) {
+	var sub sharedModels.WebhookSubscription
+	if err := d.DB.WithContext(ctx).First(&sub, id).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateSubscription changes the URL, events, description and active flag of a subscription.
+func (d *Dispatcher) UpdateSubscription(ctx context.Context, id int64, req SubscriptionRequest) (*sharedModels.WebhookSubscription, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	if err := d.checkHost(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	sub, err := d.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"url":         req.URL,
+		"events":      datatypes.NewJSONSlice(req.Events),
+		"description": req.Description,
+	}
+	switch {
+	case req.Active == nil || *req.Active == sub.Active:
+	case *req.Active:
+		updates["active"] = true
+		updates["consecutive_failures"] = 0
+		updates["disabled_at"] = nil
+		updates["disabled_reason"] = ""
+	default:
+		updates["active"] = false
+		updates["disabled_at"] = time.Now()
+		updates["disabled_reason"] = "disabled manually"
+	}
+
+	if err := d.DB.WithContext(ctx).Model(sub).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription %d: %w", id, err)
+	}
+	return d.GetSubscription(ctx, id)
+}
+
+// DeleteSubscription removes a subscription with its deliveries.
+func (d *Dispatcher) DeleteSubscription(ctx context.Context, id int64) error {
+	result := d.DB.WithContext(ctx).Delete(&sharedModels.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook subscription %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RotateSecret gives a subscription a new secret, found in its Secret field. Deliveries are signed with
+// it from their next attempt on.
+func (d *Dispatcher) RotateSecret(ctx context.Context, id int64) (*sharedModels.WebhookSubscription, error) {
+	sub, err := d.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Secret, err = NewSecret(); err != nil {
+		return nil, err
+	}
+	if err := d.DB.WithContext(ctx).Model(sub).Update("secret", sub.Secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret %d: %w", id, err)
+	}
+	return sub, nil
+}
+
+// DeliveryFilter selects deliveries in ListDeliveries. Zero fields match everything.
+type DeliveryFilter struct {
+	SubscriptionID int64
+	Status         string
+	EventType      string
+	EventID        string
+}
+
+// Page is one page of webhook deliveries, see queue.Page.
+type Page = queue.Page[sharedModels.WebhookDelivery]
+
+// ListDeliveries returns the deliveries matching filter, newest first.
+func (d *Dispatcher) ListDeliveries(ctx context.Context, filter DeliveryFilter, cursor int64, limit int) (*Page, error) {
+	query := d.DB.WithContext(ctx).Model(&sharedModels.WebhookDelivery{})
+	if filter.SubscriptionID > 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.EventID != "" {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+
+	page, err := queue.List(query, cursor, limit, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return page, nil
+}
+
+// GetDelivery returns a delivery with the log of its attempts.
+func (d *Dispatcher) GetDelivery(ctx context.Context, id int64) (*sharedModels.WebhookDelivery, error) {
+	var row sharedModels.WebhookDelivery
+	err := d.DB.WithContext(ctx).
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&row, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// Redeliver queues a dead or delivered event again with a fresh set of attempts. It keeps its event ID,
+// so receivers that already processed it can tell.
+func (d *Dispatcher) Redeliver(ctx context.Context, id int64) error {
+	result := d.DB.WithContext(ctx).Model(&sharedModels.WebhookDelivery{}).
+		Where("id = ? AND status IN ?", id, []string{StatusDead, StatusDelivered}).
+		Updates(map[string]interface{}{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"delivered_at":    nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to redeliver webhook %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := d.GetDelivery(ctx, id); err != nil {
+			return err
+		}
+		return ErrNotRedeliverable
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress rejects subscription URLs resolving to an internal address, so webhooks cannot be
+// used to reach the services next to the dispatcher.
+var ErrPrivateAddress = errors.New("webhook URL must not point to a private, loopback or link-local address")
+
+// ErrUnknownHost rejects subscription URLs whose host does not resolve.
+var ErrUnknownHost = errors.New("webhook URL host cannot be resolved")
+
+// Ranges that are not reachable on the internet besides the loopback, private and link-local ones
+// netip reports
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, which embeds IPv4 addresses
+}
+
+// publicAddr reports whether addr may be the target of a webhook.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewClient returns the client sending webhooks: it does not follow redirects, ignores proxy settings
+// and, unless allowPrivate, refuses to connect to anything but public addresses. The address is checked
+// when dialing, after DNS resolution, so a host cannot be re-pointed at an internal address later.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConnsPerHost = 4
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// client returns Client, or a client built by NewClient with AllowPrivate.
+func (d *Dispatcher) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	d.clientOnce.Do(func() {
+		d.defaultClient = NewClient(d.AllowPrivate)
+	})
+	return d.defaultClient
+}
+
+// checkHost resolves the host of rawURL and rejects it when one of its addresses is not public.
+func (d *Dispatcher) checkHost(ctx context.Context, rawURL string) error {
+	if d.AllowPrivate {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	host := parsed.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUnknownHost, host)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivateAddress, host, addr.Unmap())
+		}
+	}
+	return nil
+}
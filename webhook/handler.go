@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+// SubscriptionWithSecret is returned when a subscription is created or its secret rotated, the only
+// times the secret is shown.
+type SubscriptionWithSecret struct {
+	*sharedModels.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// RegisterRoutes mounts the admin endpoints of the webhooks on router, behind an admin-only middleware:
+// GET and POST /subscriptions, GET, PUT and DELETE /subscriptions/:id, POST /subscriptions/:id/rotate-secret,
+// GET /deliveries (?subscription_id=&status=&event_type=&event_id=&cursor=&limit=), GET /deliveries/:id with
+// its attempts and POST /deliveries/:id/redeliver.
+func (d *Dispatcher) RegisterRoutes(router fiber.Router) {
+	router.Get("/subscriptions", d.ListSubscriptionsHandler)
+	router.Post("/subscriptions", d.CreateSubscriptionHandler)
+	router.Get("/subscriptions/:id", d.GetSubscriptionHandler)
+	router.Put("/subscriptions/:id", d.UpdateSubscriptionHandler)
+	router.Delete("/subscriptions/:id", d.DeleteSubscriptionHandler)
+	router.Post("/subscriptions/:id/rotate-secret", d.RotateSecretHandler)
+	router.Get("/deliveries", d.ListDeliveriesHandler)
+	router.Get("/deliveries/:id", d.GetDeliveryHandler)
+	router.Post("/deliveries/:id/redeliver", d.RedeliverHandler)
+}
+
+// ListSubscriptionsHandler returns every subscription.
+func (d *Dispatcher) ListSubscriptionsHandler(c fiber.Ctx) error {
+	subs, err := d.ListSubscriptions(c.Context())
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, subs)
+}
+
+// CreateSubscriptionHandler creates the subscription in a SubscriptionRequest body and returns it with its secret.
+func (d *Dispatcher) CreateSubscriptionHandler(c fiber.Ctx) error {
+	var req SubscriptionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG)
+	}
+
+	sub, err := d.CreateSubscription(c.Context(), req)
+	if err != nil {
+		return subscriptionError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_201, respcode.SUC_CODE_201_MSG, SubscriptionWithSecret{sub, sub.Secret})
+}
+
+// GetSubscriptionHandler returns the subscription in :id.
+func (d *Dispatcher) GetSubscriptionHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid subscription ID")
+	}
+
+	sub, err := d.GetSubscription(c.Context(), id)
+	if err != nil {
+		return subscriptionError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, sub)
+}
+
+// UpdateSubscriptionHandler replaces the subscription in :id with a SubscriptionRequest body.
+func (d *Dispatcher) UpdateSubscriptionHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid subscription ID")
+	}
+	var req SubscriptionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, respcode.ERR_CODE_400_MSG)
+	}
+
+	sub, err := d.UpdateSubscription(c.Context(), id, req)
+	if err != nil {
+		return subscriptionError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, sub)
+}
+
+// DeleteSubscriptionHandler deletes the subscription in :id with its deliveries.
+func (d *Dispatcher) DeleteSubscriptionHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid subscription ID")
+	}
+
+	if err := d.DeleteSubscription(c.Context(), id); err != nil {
+		return subscriptionError(c, err)
+	}
+	return helper.JSONResponse(c, respcode.SUC_CODE_DELETE, respcode.SUC_CODE_DELETE_MSG)
+}
+
+// RotateSecretHandler gives the subscription in :id a new secret and returns it.
+func (d *Dispatcher) RotateSecretHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid subscription ID")
+	}
+
+	sub, err := d.RotateSecret(c.Context(), id)
+	if err != nil {
+		return subscriptionError(c, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG, SubscriptionWithSecret{sub, sub.Secret})
+}
+
+// ListDeliveriesHandler returns one page of deliveries.
+func (d *Dispatcher) ListDeliveriesHandler(c fiber.Ctx) error {
+	filter := DeliveryFilter{
+		SubscriptionID: fiber.Query[int64](c, "subscription_id", 0),
+		Status:         c.Query("status"),
+		EventType:      c.Query("event_type"),
+		EventID:        c.Query("event_id"),
+	}
+	page, err := d.ListDeliveries(c.Context(), filter, fiber.Query[int64](c, "cursor", 0), fiber.Query[int](c, "limit", 20))
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, page)
+}
+
+// GetDeliveryHandler returns the delivery in :id with the log of its attempts.
+func (d *Dispatcher) GetDeliveryHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid delivery ID")
+	}
+
+	row, err := d.GetDelivery(c.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	}
+	if err != nil {
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponseWithData(c, respcode.SUC_CODE_FETCH, respcode.SUC_CODE_FETCH_MSG, row)
+}
+
+// RedeliverHandler queues the dead or delivered event in :id again.
+func (d *Dispatcher) RedeliverHandler(c fiber.Ctx) error {
+	id, ok := paramID(c)
+	if !ok {
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, "Invalid delivery ID")
+	}
+
+	err := d.Redeliver(c.Context(), id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, ErrNotRedeliverable):
+		return helper.JSONResponse(c, respcode.ERR_CODE_409, "Only dead or delivered webhooks can be redelivered")
+	case err != nil:
+		return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+	}
+	return helper.JSONResponse(c, respcode.SUC_CODE_UPDATE, respcode.SUC_CODE_UPDATE_MSG)
+}
+
+// subscriptionError answers a failed subscription operation.
+func subscriptionError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return helper.JSONResponse(c, respcode.ERR_CODE_404, respcode.ERR_CODE_404_MSG)
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrPrivateAddress), errors.Is(err, ErrUnknownHost),
+		errors.Is(err, ErrNoEvents):
+		return helper.JSONResponse(c, respcode.ERR_CODE_400, err.Error())
+	}
+	return helper.JSONResponseWithError(c, respcode.ERR_CODE_500, respcode.ERR_CODE_500_MSG, err)
+}
+
+func paramID(c fiber.Ctx) (int64, bool) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	return id, err == nil
+}
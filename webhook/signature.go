@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DevdotSP/go-utils/helper"
+	"github.com/DevdotSP/go-utils/respcode"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Headers of a webhook request
+const (
+	HeaderID        = "X-Webhook-ID"        // Event ID, the same on every attempt so receivers can drop duplicates
+	HeaderEvent     = "X-Webhook-Event"     // Event type
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix time of the attempt
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+)
+
+// DefaultTolerance is how old a signed request may be before Verify rejects it as a replay
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredTimestamp = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature header value of body sent at timestamp with secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a received webhook body against any of secrets,
+// several of them allowing a secret to be rotated. A zero tolerance uses DefaultTolerance.
+func Verify(timestamp, signature string, body []byte, tolerance time.Duration, secrets ...string) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	sentAt := time.Unix(unix, 0)
+	if age := time.Since(sentAt); age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	for _, secret := range secrets {
+		if secret != "" && hmac.Equal([]byte(Sign(secret, sentAt, body)), []byte(strings.TrimSpace(signature))) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyMiddleware rejects incoming webhooks not signed with one of secrets, or older than tolerance,
+// e.g. app.Post("/webhooks/partner", handler, webhook.VerifyMiddleware(0, secret)).
+func VerifyMiddleware(tolerance time.Duration, secrets ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		err := Verify(c.Get(HeaderTimestamp), c.Get(HeaderSignature), c.Body(), tolerance, secrets...)
+		if err != nil {
+			return helper.JSONResponseWithError(c, respcode.ERR_CODE_401, respcode.ERR_CODE_401_MSG, err)
+		}
+		return c.Next()
+	}
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
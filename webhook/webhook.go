@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DevdotSP/go-utils/queue"
+	sharedModels "github.com/DevdotSP/go-utils/shared-models"
+	"github.com/DevdotSP/go-utils/utils"
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventUserCreated     = "user.created"
+	EventUserRoleChanged = "user.role_changed"
+	EventExportFinished  = "export.finished"
+	EventAll             = "*" // Subscribes to every event
+)
+
+// Delivery statuses
+const (
+	StatusPending   = queue.StatusPending
+	StatusSending   = queue.StatusSending
+	StatusDelivered = "delivered"
+	StatusDead      = queue.StatusDead // Only sent again through Redeliver
+)
+
+// Event is the JSON body of a webhook request.
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to webhook subscriptions. Each event is queued for every subscription to
+// it, within the transaction that triggers it, and the workers of its Pool POST the deliveries. Each
+// request carries the event ID and a timestamped HMAC-SHA256 signature, see Sign and Verify.
+type Dispatcher struct {
+	DB *gorm.DB
+	queue.Pool
+
+	Timeout      time.Duration // Per request
+	DisableAfter int           // Consecutive failed attempts after which a subscription is disabled, never when 0
+
+	// Client sends the requests; defaults to NewClient(AllowPrivate)
+	Client *http.Client
+	// AllowPrivate accepts subscriptions to private, loopback and link-local addresses, e.g. in development
+	AllowPrivate bool
+
+	clientOnce    sync.Once
+	defaultClient *http.Client
+}
+
+// New returns a Dispatcher with 4 workers and 8 attempts from 30 seconds apart up to an hour, which
+// disables a subscription after 20 consecutive failed attempts.
+func New(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		DB: db,
+		Pool: queue.Pool{
+			Name:         "webhook delivery",
+			Workers:      4,
+			BatchSize:    10,
+			PollInterval: 2 * time.Second,
+			Lease:        5 * time.Minute,
+			MaxAttempts:  8,
+			Backoff:      30 * time.Second,
+			MaxBackoff:   time.Hour,
+		},
+		Timeout:      10 * time.Second,
+		DisableAfter: 20,
+	}
+}
+
+// Dispatch queues an event of eventType with data for every active subscription to it, in tx, the
+// transaction of the business write it belongs to. It returns the event, which is not queued when
+// nobody subscribes to it.
+func (d *Dispatcher) Dispatch(tx *gorm.DB, eventType string, data interface{}) (*Event, error) {
+	event := &Event{ID: utils.GenerateUUID(), Type: eventType, CreatedAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	filter, _ := json.Marshal([]string{eventType})
+	var subscriptionIDs []int64
+	err = tx.Model(&sharedModels.WebhookSubscription{}).
+		Where("active AND (events @> ?::jsonb OR events @> ?::jsonb)", string(filter), `["*"]`).
+		Pluck("id", &subscriptionIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook subscriptions: %w", err)
+	}
+	if len(subscriptionIDs) == 0 {
+		return event, nil
+	}
+
+	rows := make([]sharedModels.WebhookDelivery, len(subscriptionIDs))
+	for i, id := range subscriptionIDs {
+		rows[i] = sharedModels.WebhookDelivery{
+			SubscriptionID: id,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         StatusPending,
+			MaxAttempts:    d.MaxAttempts,
+			NextAttemptAt:  time.Now(),
+		}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to dispatch event: %w", err)
+	}
+	return event, nil
+}
+
+// Start sends the queued deliveries until ctx is cancelled. Wait returns once the workers have stopped.
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.Pool.Start(ctx, d.ProcessBatch)
+}
+
+// ProcessBatch sends up to BatchSize due deliveries, returning how many it claimed. It is exported to
+// drain the queue synchronously, e.g. in tests. Deliveries of a disabled subscription wait until it
+// is enabled again.
+func (d *Dispatcher) ProcessBatch(ctx context.Context) (int, error) {
+	return queue.Run(ctx, d.DB, &d.Pool, func(query *gorm.DB) *gorm.DB {
+		active := d.DB.Model(&sharedModels.WebhookSubscription{}).Select("id").Where("active")
+		return query.Where("subscription_id IN (?)", active).Preload("Subscription")
+	}, deliveryID, d.process)
+}
+
+func deliveryID(row *sharedModels.WebhookDelivery) int64 {
+	return row.ID
+}
+
+// process sends one claimed delivery and records the attempt.
+func (d *Dispatcher) process(ctx context.Context, row *sharedModels.WebhookDelivery) error {
+	db := d.DB.WithContext(ctx)
+	sub := row.Subscription
+
+	started := time.Now()
+	statusCode, responseBody, sendErr := d.send(ctx, row, sub, started)
+	if sendErr != nil && ctx.Err() != nil {
+		// Shutting down: leave the delivery to the next worker once the lease expires
+		return nil
+	}
+
+	attempt := row.Attempts + 1
+	entry := sharedModels.WebhookAttempt{
+		DeliveryID:   row.ID,
+		Attempt:      attempt,
+		StatusCode:   statusCode,
+		ResponseBody: truncate(string(responseBody), maxResponseBody),
+		Duration:     time.Since(started).Milliseconds(),
+	}
+	updates := d.Attempt(attempt, row.MaxAttempts, sendErr, false, StatusDelivered)
+	updates["last_status_code"] = entry.StatusCode
+	if sendErr == nil {
+		updates["delivered_at"] = time.Now()
+	} else {
+		entry.Error = sendErr.Error()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("failed to log webhook attempt: %w", err)
+		}
+		if err := d.Update(tx, &sharedModels.WebhookDelivery{}, row.ID, updates); err != nil {
+			return err
+		}
+		if sendErr == nil {
+			return tx.Model(sub).Where("consecutive_failures > 0").Update("consecutive_failures", 0).Error
+		}
+		return d.recordFailure(tx, sub, entry.StatusCode)
+	})
+}
+
+// maxResponseBody is the part of the response body kept in the attempt log, the rest is never read
+const maxResponseBody = 1024
+
+// send POSTs the signed payload of row to sub. Anything but a 2xx answer is an error.
+func (d *Dispatcher) send(ctx context.Context, row *sharedModels.WebhookDelivery, sub *sharedModels.WebhookSubscription, now time.Time) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+
+	body := []byte(row.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, row.EventID)
+	req.Header.Set(HeaderEvent, row.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, now, body))
+
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return resp.StatusCode, responseBody, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, responseBody, fmt.Errorf("endpoint returned %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp.StatusCode, responseBody, nil
+}
+
+// recordFailure counts a failed attempt against sub and disables it after DisableAfter in a row, or at
+// once when the endpoint answers 410 Gone.
+func (d *Dispatcher) recordFailure(tx *gorm.DB, sub *sharedModels.WebhookSubscription, statusCode int) error {
+	err := tx.Model(sub).UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil {
+		return fmt.Errorf("failed to update webhook subscription %d: %w", sub.ID, err)
+	}
+
+	reason := ""
+	query := tx.Model(&sharedModels.WebhookSubscription{}).Where("id = ? AND active", sub.ID)
+	switch {
+	case statusCode == http.StatusGone:
+		reason = "endpoint returned 410 Gone"
+	case d.DisableAfter > 0:
+		reason = fmt.Sprintf("%d consecutive failed attempts", d.DisableAfter)
+		query = query.Where("consecutive_failures >= ?", d.DisableAfter)
+	default:
+		return nil
+	}
+
+	result := query.Updates(map[string]interface{}{"active": false, "disabled_at": time.Now(), "disabled_reason": reason})
+	if result.Error != nil {
+		return fmt.Errorf("failed to disable webhook subscription %d: %w", sub.ID, result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("⚠️ Webhook subscription %d to %s disabled: %s", sub.ID, sub.URL, reason)
+	}
+	return nil
+}
+
+// truncate cuts s to n bytes of valid UTF-8 that PostgreSQL accepts in a text column
+func truncate(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+}